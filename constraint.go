@@ -0,0 +1,154 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConstraintKind is the kind of rule of a Constraint.
+type ConstraintKind int
+
+const ( // The kinds of constraints between options.
+	// ConstraintExclusive allows at most one of the options to be set.
+	ConstraintExclusive ConstraintKind = iota
+	// ConstraintExactlyOne requires exactly one of the options to be set.
+	ConstraintExactlyOne
+	// ConstraintTogether requires either all or none of the options to be
+	// set.
+	ConstraintTogether
+	// ConstraintRequires requires the other options to be set if the first
+	// option is set.
+	ConstraintRequires
+)
+
+// Constraint is a rule about which options can be set together.  An option
+// is set if any source, like the config file or the command line flags, sets
+// it.  Default values don't count.
+type Constraint struct {
+	Kind ConstraintKind
+	// Options are the full IDs of the options, like "tls.cert".
+	Options []string
+}
+
+// MutuallyExclusive creates a constraint that allows at most one of the
+// options to be set.
+func MutuallyExclusive(ids ...string) Constraint {
+	return Constraint{ConstraintExclusive, ids}
+}
+
+// ExactlyOneOf creates a constraint that requires exactly one of the options
+// to be set.
+func ExactlyOneOf(ids ...string) Constraint {
+	return Constraint{ConstraintExactlyOne, ids}
+}
+
+// RequiredTogether creates a constraint that requires either all or none of
+// the options to be set.
+func RequiredTogether(ids ...string) Constraint {
+	return Constraint{ConstraintTogether, ids}
+}
+
+// Requires creates a constraint that requires the other options to be set if
+// the option with the given ID is set.
+func Requires(id string, required ...string) Constraint {
+	return Constraint{ConstraintRequires, append([]string{id}, required...)}
+}
+
+// String describes the constraint in terms of the command line flags, like it
+// is shown in the help message.
+func (c Constraint) String() string {
+	flags := make([]string, len(c.Options))
+	for i, id := range c.Options {
+		flags[i] = "--" + id
+	}
+
+	switch c.Kind {
+	case ConstraintExclusive:
+		return joinAnd(flags) + " are mutually exclusive"
+	case ConstraintExactlyOne:
+		return "exactly one of " + joinAnd(flags) + " is required"
+	case ConstraintTogether:
+		return joinAnd(flags) + " must be set together"
+	case ConstraintRequires:
+		return flags[0] + " requires " + joinAnd(flags[1:])
+	}
+	return fmt.Sprintf("unknown constraint kind %d", c.Kind)
+}
+
+// joinAnd joins the words like "a, b and c".
+func joinAnd(words []string) string {
+	if len(words) <= 1 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
+
+// findOption returns the option with the given full ID, or nil.
+func findOption(s *setup, id string) *option {
+	for _, opt := range s.allOpts {
+		if opt.fullID() == id {
+			return opt
+		}
+	}
+	return nil
+}
+
+// checkConstraintOptions checks that the constraints from the conf refer to
+// existing options that are not nested structs.
+func checkConstraintOptions(s *setup) error {
+	for _, c := range s.conf.Constraints {
+		if c.Kind < ConstraintExclusive || c.Kind > ConstraintRequires {
+			return fmt.Errorf("unknown constraint kind %d", c.Kind)
+		}
+		if len(c.Options) < 2 {
+			return fmt.Errorf("constraint '%v' needs at least two options", c)
+		}
+		for _, id := range c.Options {
+			opt := findOption(s, id)
+			if opt == nil {
+				return fmt.Errorf("constraint '%v' refers to unknown option %v", c, id)
+			}
+			if opt.isParent {
+				return fmt.Errorf("constraint '%v' refers to nested struct %v", c, id)
+			}
+		}
+	}
+	return nil
+}
+
+// checkConstraints checks the constraints from the conf after all sources have
+// been parsed.
+func checkConstraints(s *setup) error {
+	var errs LoadErrors
+	for _, c := range s.conf.Constraints {
+		err := &ConstraintError{Constraint: c}
+		for _, id := range c.Options {
+			if opt := findOption(s, id); opt.source != "" {
+				err.Set = append(err.Set, id)
+				err.Sources = append(err.Sources, opt.source)
+			} else {
+				err.Missing = append(err.Missing, id)
+			}
+		}
+
+		violated := false
+		switch c.Kind {
+		case ConstraintExclusive:
+			violated = len(err.Set) > 1
+		case ConstraintExactlyOne:
+			violated = len(err.Set) != 1
+		case ConstraintTogether:
+			violated = len(err.Set) > 0 && len(err.Missing) > 0
+		case ConstraintRequires:
+			violated = findOption(s, c.Options[0]).source != "" && len(err.Missing) > 0
+		}
+		if violated {
+			errs = appendError(errs, err)
+		}
+	}
+	return errs.errorOrNil()
+}
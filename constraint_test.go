@@ -0,0 +1,108 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type constraintConfig struct {
+	Token     string
+	TokenFile string `id:"token-file"`
+	TLS       struct {
+		Cert string
+		Key  string
+	} `id:"tls"`
+	Debug bool `default:"true"`
+}
+
+var testConstraints = []Constraint{
+	ExactlyOneOf("token", "token-file"),
+	Requires("tls.cert", "tls.key"),
+}
+
+func TestLoad_Constraints(t *testing.T) {
+	load := func(args []string, env map[string]string) error {
+		setOS(args, env)
+		config := constraintConfig{}
+		return Load(&config, Conf{EnvPrefix: "APP_", Constraints: testConstraints})
+	}
+
+	require.NoError(t, load([]string{"--token", "x"}, nil))
+	require.NoError(t, load([]string{"--token-file", "f", "--tls.cert", "c"},
+		map[string]string{"APP_TLS_KEY": "k"}))
+
+	var constraintErr *ConstraintError
+	err := load([]string{"--token", "x"}, map[string]string{"APP_TOKEN_FILE": "f"})
+	require.True(t, errors.As(err, &constraintErr))
+	assert.Equal(t, []string{"token", "token-file"}, constraintErr.Set)
+	assert.Equal(t, []Source{SourceFlag, SourceEnv}, constraintErr.Sources)
+	assert.EqualError(t, err, "options 'token' (from command line flags) and "+
+		"'token-file' (from environment) are mutually exclusive")
+
+	err = load(nil, nil)
+	assert.EqualError(t, err, "exactly one of the options 'token' and 'token-file' is required")
+
+	err = load([]string{"--token", "x", "--tls.cert", "c"}, nil)
+	require.True(t, errors.As(err, &constraintErr))
+	assert.Equal(t, []string{"tls.key"}, constraintErr.Missing)
+	assert.EqualError(t, err, "option 'tls.cert' (from command line flags) requires 'tls.key'")
+
+	// Default values don't count as set.
+	setOS([]string{"--token", "x"}, nil)
+	config := constraintConfig{}
+	require.NoError(t, Load(&config, Conf{Constraints: []Constraint{
+		MutuallyExclusive("token", "debug"),
+	}}))
+}
+
+func TestLoad_ConstraintTogether(t *testing.T) {
+	config := constraintConfig{}
+	err := LoadMap(&config, map[string]interface{}{
+		"tls": map[string]interface{}{"key": "k"},
+	}, Conf{Constraints: []Constraint{RequiredTogether("tls.cert", "tls.key")}})
+	assert.EqualError(t, err, "options 'tls.cert' and 'tls.key' must be set "+
+		"together, but only 'tls.key' (from map) is set")
+}
+
+func TestCheckConstraintOptions(t *testing.T) {
+	config := constraintConfig{}
+	for _, c := range []struct {
+		constraint Constraint
+		err        string
+	}{
+		{MutuallyExclusive("token"),
+			"constraint '--token are mutually exclusive' needs at least two options"},
+		{Requires("token", "nope"),
+			"constraint '--token requires --nope' refers to unknown option nope"},
+		{RequiredTogether("token", "tls"),
+			"constraint '--token and --tls must be set together' refers to nested struct tls"},
+	} {
+		s := &setup{conf: &Conf{Constraints: []Constraint{c.constraint}}}
+		require.NoError(t, inspectConfigStructure(s, &config))
+		assert.EqualError(t, checkConstraintOptions(s), c.err)
+	}
+}
+
+func TestHelpMessage_Constraints(t *testing.T) {
+	config := constraintConfig{}
+	s := &setup{conf: &Conf{Constraints: append(testConstraints,
+		MutuallyExclusive("token", "tls.cert", "debug"),
+		RequiredTogether("tls.cert", "tls.key"))}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+
+	var buf bytes.Buffer
+	writeHelpMessage(s, &buf, false)
+	assert.Contains(t, buf.String(), "\nConstraints:\n"+
+		"  exactly one of --token and --token-file is required\n"+
+		"  --tls.cert requires --tls.key\n"+
+		"  --token, --tls.cert and --debug are mutually exclusive\n"+
+		"  --tls.cert and --tls.key must be set together\n")
+}
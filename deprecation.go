@@ -0,0 +1,213 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// warn logs a warning using the logger from the conf.  Every warning is only
+// logged once, even if the sources are parsed several times.
+func warn(s *setup, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if s.warned[msg] {
+		return
+	}
+	if s.warned == nil {
+		s.warned = make(map[string]bool)
+	}
+	s.warned[msg] = true
+
+	logger := s.conf.Logger
+	if logger == nil {
+		logger = log.Printf
+	}
+	logger("%s", msg)
+}
+
+// checkAliases checks that the aliases of the options do not conflict with
+// the options or with each other.
+func checkAliases(allOpts []*option) error {
+	ids := make(map[string]bool)
+	for _, opt := range allOpts {
+		ids[opt.fullID()] = true
+	}
+	for _, opt := range allOpts {
+		if len(opt.aliases) > 0 && opt.isParent {
+			return fmt.Errorf("option %v has aliases, which are not supported "+
+				"for nested structs", opt.fullID())
+		}
+		for _, alias := range opt.aliases {
+			id := strings.Join(alias, ".")
+			if ids[id] {
+				return fmt.Errorf("alias %v of option %v is already in use",
+					id, opt.fullID())
+			}
+			ids[id] = true
+		}
+	}
+	return nil
+}
+
+// resolveFlagAliases renames the flags that are set with an alias to the
+// full ID of their option.  If both the alias and the option are set, the
+// option takes precedence.
+func resolveFlagAliases(s *setup, flags map[string]string) {
+	for _, opt := range s.allOpts {
+		for _, alias := range opt.aliases {
+			id := strings.Join(alias, ".")
+			for flag, value := range flags {
+				var newFlag string
+				if flag == id && !opt.isMap {
+					newFlag = opt.fullID()
+				} else if opt.isMap && strings.HasPrefix(flag, id+".") {
+					newFlag = opt.fullID() + strings.TrimPrefix(flag, id)
+				} else {
+					continue
+				}
+
+				warn(s, "flag --%v is deprecated, use --%v instead", id, opt.fullID())
+				delete(flags, flag)
+				if _, set := flags[newFlag]; !set {
+					flags[newFlag] = value
+				}
+			}
+		}
+	}
+}
+
+// resolveEnvAliases returns a copy of the environment variables in which the
+// variables for aliases are renamed to the variable for their option.  If
+// both are set, the variable for the option takes precedence.
+func resolveEnvAliases(s *setup, vars map[string]string) map[string]string {
+	result := make(map[string]string, len(vars))
+	for key, value := range vars {
+		result[key] = value
+	}
+
+	for _, opt := range s.allOpts {
+		envKey := makeEnvKey(s.conf.EnvPrefix, opt.fullIDParts)
+		for _, alias := range opt.aliases {
+			aliasKey := makeEnvKey(s.conf.EnvPrefix, alias)
+			for key, value := range vars {
+				var newKey string
+				if key == aliasKey && !opt.isMap {
+					newKey = envKey
+				} else if opt.isMap && strings.HasPrefix(key, aliasKey+"_") {
+					newKey = envKey + strings.TrimPrefix(key, aliasKey)
+				} else {
+					continue
+				}
+
+				warn(s, "environment variable %v is deprecated, use %v instead",
+					aliasKey, envKey)
+				delete(result, key)
+				if _, set := vars[newKey]; !set {
+					result[newKey] = value
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// resolveMapAliases moves the values for aliases in the nested map to the
+// location of their option, together with their positions.  If both are set,
+// the value for the option takes precedence.
+func resolveMapAliases(s *setup, m map[string]interface{},
+	positions map[string]Position, source Source) {
+	for _, opt := range s.allOpts {
+		for _, alias := range opt.aliases {
+			value, found := removeMapPath(m, alias)
+			if !found {
+				continue
+			}
+
+			id := strings.Join(alias, ".")
+			warn(s, "%v key %v is deprecated, use %v instead", source, id, opt.fullID())
+			if !setMapPath(m, opt.fullIDParts, value) {
+				continue
+			}
+			for key, pos := range positions {
+				if key == id || strings.HasPrefix(key, id+".") {
+					positions[opt.fullID()+strings.TrimPrefix(key, id)] = pos
+				}
+			}
+		}
+	}
+}
+
+// copyMap returns a shallow copy of the map.
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for key, val := range m {
+		result[key] = val
+	}
+	return result
+}
+
+// removeMapPath removes the value at the path of keys from the nested map and
+// returns it.  The nested maps along the path are copied, so that only m
+// itself is modified.
+func removeMapPath(m map[string]interface{}, path []string) (interface{}, bool) {
+	for _, key := range path[:len(path)-1] {
+		sub, ok := m[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		sub = copyMap(sub)
+		m[key] = sub
+		m = sub
+	}
+
+	last := path[len(path)-1]
+	value, found := m[last]
+	delete(m, last)
+	return value, found
+}
+
+// setMapPath sets the value at the path of keys in the nested map, creating
+// the intermediate maps.  It returns false if there already is a value.  Like
+// removeMapPath, the nested maps along the path are copied.
+func setMapPath(m map[string]interface{}, path []string, value interface{}) bool {
+	for _, key := range path[:len(path)-1] {
+		sub, ok := m[key].(map[string]interface{})
+		if ok {
+			sub = copyMap(sub)
+		} else if _, set := m[key]; set {
+			return false
+		} else {
+			sub = make(map[string]interface{})
+		}
+		m[key] = sub
+		m = sub
+	}
+
+	last := path[len(path)-1]
+	if _, set := m[last]; set {
+		return false
+	}
+	m[last] = value
+	return true
+}
+
+// warnDeprecatedOptions logs a warning for all options with the deprecated
+// tag that have been set by any source.
+func warnDeprecatedOptions(s *setup) {
+	for _, opt := range s.allOpts {
+		if !opt.isDeprecated || opt.source == "" {
+			continue
+		}
+		if opt.deprecated == "" {
+			warn(s, "option %v is deprecated (set in %v)", opt.fullID(), opt.source)
+		} else {
+			warn(s, "option %v is deprecated (set in %v): %v", opt.fullID(),
+				opt.source, opt.deprecated)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type deprecationConfig struct {
+	Server struct {
+		Address string `alias:"listen,bind"`
+	}
+	Labels  map[string]interface{} `alias:"tags"`
+	Workers int                    `deprecated:"use server.threads"`
+	Debug   bool                   `deprecated:""`
+}
+
+func TestLoad_Aliases(t *testing.T) {
+	var warnings []string
+	logger := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
+	// Aliases in the command line flags.
+	setOS([]string{"--listen", ":80", "--tags.a", "1", "--tags.a", "2"}, nil)
+	config := deprecationConfig{}
+	require.NoError(t, Load(&config, Conf{Logger: logger}))
+	assert.Equal(t, ":80", config.Server.Address)
+	assert.Equal(t, map[string]interface{}{"a": "1,2"}, config.Labels)
+	assert.Equal(t, []string{
+		"flag --listen is deprecated, use --server.address instead",
+		"flag --tags is deprecated, use --labels instead",
+	}, warnings)
+
+	// Aliases in the environment, where the new name takes precedence.
+	warnings = nil
+	setOS(nil, map[string]string{
+		"APP_BIND":           ":81",
+		"APP_SERVER_ADDRESS": ":82",
+		"APP_TAGS_B":         "3",
+	})
+	config = deprecationConfig{}
+	require.NoError(t, Load(&config, Conf{EnvPrefix: "APP_", EnvStrict: true, Logger: logger}))
+	assert.Equal(t, ":82", config.Server.Address)
+	assert.Equal(t, map[string]interface{}{"b": "3"}, config.Labels)
+	assert.Equal(t, []string{
+		"environment variable APP_BIND is deprecated, use APP_SERVER_ADDRESS instead",
+		"environment variable APP_TAGS is deprecated, use APP_LABELS instead",
+	}, warnings)
+
+	// Aliases in the config file.
+	warnings = nil
+	setOS(nil, nil)
+	config = deprecationConfig{}
+	require.NoError(t, LoadRawFile(&config, []byte("listen: \":83\"\ntags:\n  c: 4\n"),
+		Conf{FileDecoder: DecoderYAML, FileStrict: true, Logger: logger}))
+	assert.Equal(t, ":83", config.Server.Address)
+	assert.Equal(t, map[string]interface{}{"c": 4}, config.Labels)
+	assert.Equal(t, []string{
+		"config file key listen is deprecated, use server.address instead",
+		"config file key tags is deprecated, use labels instead",
+	}, warnings)
+}
+
+func TestLoad_AliasPositions(t *testing.T) {
+	config := struct {
+		Port int `alias:"old.port"`
+	}{}
+	err := LoadRawFile(&config, []byte("old:\n  port: x\n"), Conf{
+		FileDecoder: DecoderYAML,
+		Logger:      func(string, ...interface{}) {},
+	})
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 2, parseErr.Position.Line)
+}
+
+func TestLoadWithMap_AliasesDoNotModifyMap(t *testing.T) {
+	setOS(nil, nil)
+	m := map[string]interface{}{"server": map[string]interface{}{"old": "x"}}
+	config := struct {
+		Server struct {
+			Address string `alias:"server.old"`
+		}
+	}{}
+	require.NoError(t, LoadMap(&config, m, Conf{Logger: func(string, ...interface{}) {}}))
+	assert.Equal(t, "x", config.Server.Address)
+	assert.Equal(t, map[string]interface{}{"server": map[string]interface{}{"old": "x"}}, m)
+}
+
+func TestLoad_Deprecated(t *testing.T) {
+	var warnings []string
+	logger := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
+	setOS([]string{"--workers", "2", "--debug"}, nil)
+	config := deprecationConfig{}
+	require.NoError(t, Load(&config, Conf{Logger: logger}))
+	assert.Equal(t, []string{
+		"option workers is deprecated (set in command line flags): use server.threads",
+		"option debug is deprecated (set in command line flags)",
+	}, warnings)
+
+	// Options that are not set don't give a warning.
+	warnings = nil
+	setOS(nil, nil)
+	require.NoError(t, Load(&config, Conf{Logger: logger}))
+	assert.Empty(t, warnings)
+}
+
+func TestCheckAliases(t *testing.T) {
+	config := struct {
+		Name  string
+		Other string `alias:"name"`
+	}{}
+	err := inspectConfigStructure(&setup{conf: &Conf{}}, &config)
+	assert.EqualError(t, err, "alias name of option other is already in use")
+}
+
+func TestHelpMessage_Deprecated(t *testing.T) {
+	config := deprecationConfig{}
+	s := &setup{conf: &Conf{HelpMessage: "Usage:"}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+
+	var buf bytes.Buffer
+	writeHelpMessage(s, &buf, false)
+	assert.Contains(t, buf.String(), "--workers int              (deprecated: use server.threads)\n")
+	assert.Contains(t, buf.String(), "--debug                    (deprecated)\n")
+	assert.NotContains(t, buf.String(), "listen")
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ManPage holds the information for a man page that is not in the config
+// struct.
+type ManPage struct {
+	// Name is the name of the program.  The default is the name of the
+	// executable.
+	Name string
+	// Section is the section of the manual.  The default is "1".
+	Section string
+	// Description is the one-line description of the program in the NAME
+	// section.
+	Description string
+	// Date is the date of the last change to the page.
+	Date string
+	// Source is the source of the program, like its name and version.
+	Source string
+	// Manual is the title of the manual.
+	Manual string
+}
+
+// WriteManPage writes a man page in roff format for the config struct at c,
+// as it would be loaded by Load with the given conf.  The page has a NAME,
+// SYNOPSIS and OPTIONS section, an ENVIRONMENT section if environment
+// variables are enabled and a FILES section if config files are enabled.
+// Options that are hidden from the help message are left out.
+func WriteManPage(w io.Writer, c interface{}, conf Conf, page ManPage) error {
+	help, err := NewHelp(c, conf, true)
+	if err != nil {
+		return err
+	}
+
+	name := page.Name
+	if name == "" {
+		name = path.Base(os.Args[0])
+	}
+	section := page.Section
+	if section == "" {
+		section = "1"
+	}
+
+	fmt.Fprintf(w, ".TH %v %v %q %q %q\n", roffEscape(strings.ToUpper(name)),
+		section, page.Date, page.Source, page.Manual)
+
+	fmt.Fprintln(w, ".SH NAME")
+	if page.Description != "" {
+		fmt.Fprintf(w, "%v \\- %v\n", roffEscape(name), roffEscape(page.Description))
+	} else {
+		fmt.Fprintln(w, roffEscape(name))
+	}
+
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	fmt.Fprintf(w, ".B %v\n", roffEscape(name))
+	fmt.Fprintln(w, `[\fIOPTIONS\fR]`)
+
+	fmt.Fprintln(w, ".SH OPTIONS")
+	for _, sec := range help.Sections {
+		if len(sec.Options) == 0 {
+			continue
+		}
+		if sec.Title != "" {
+			fmt.Fprintf(w, ".SS %v\n", roffEscape(sec.Title))
+		}
+		for _, opt := range sec.Options {
+			fmt.Fprintln(w, ".TP")
+			fmt.Fprintln(w, roffFlag(opt))
+			fmt.Fprintln(w, roffEscape(helpDescription(&Help{}, opt)))
+		}
+	}
+
+	var envOpts, fileOpts []*HelpOption
+	for _, sec := range help.Sections {
+		for _, opt := range sec.Options {
+			if opt.EnvName != "" {
+				envOpts = append(envOpts, opt)
+			}
+			if opt.FileKey != "" {
+				fileOpts = append(fileOpts, opt)
+			}
+		}
+	}
+
+	if len(envOpts) > 0 {
+		fmt.Fprintln(w, ".SH ENVIRONMENT")
+		for _, opt := range envOpts {
+			fmt.Fprintln(w, ".TP")
+			fmt.Fprintf(w, ".B %v\n", roffEscape(opt.EnvName))
+			fmt.Fprintf(w, "Sets \\fB\\-\\-%v\\fR.\n", roffEscape(opt.FullID))
+		}
+	}
+
+	if len(fileOpts) > 0 {
+		fmt.Fprintln(w, ".SH FILES")
+		files := help.SearchPaths
+		if len(files) == 0 && conf.FileDefaultFilename != "" {
+			files = []string{conf.FileDefaultFilename}
+		}
+		for _, file := range files {
+			fmt.Fprintln(w, ".TP")
+			fmt.Fprintf(w, ".I %v\n", roffEscape(file))
+			fmt.Fprintln(w, "The config file.")
+		}
+		fmt.Fprintln(w, ".PP")
+		fmt.Fprintln(w, "The options can be set in the config file with the following keys:")
+		for _, opt := range fileOpts {
+			fmt.Fprintln(w, ".TP")
+			fmt.Fprintf(w, ".B %v\n", roffEscape(opt.FileKey))
+			fmt.Fprintf(w, "Sets \\fB\\-\\-%v\\fR.\n", roffEscape(opt.FullID))
+		}
+	}
+
+	return nil
+}
+
+// WriteMarkdownReference writes a Markdown reference of the options of the
+// config struct at c, as it would be loaded by Load with the given conf.  It
+// has a table for every section of the help message, with the environment
+// variable and config file key of every option if they are enabled.  Options
+// that are hidden from the help message and built-in flags like --help are
+// left out.
+func WriteMarkdownReference(w io.Writer, c interface{}, conf Conf) error {
+	help, err := NewHelp(c, conf, true)
+	if err != nil {
+		return err
+	}
+
+	help.ShowValues = false
+	for _, sec := range help.Sections {
+		opts := sec.Options[:0]
+		for _, opt := range sec.Options {
+			if !opt.Builtin {
+				opts = append(opts, opt)
+			}
+		}
+		sec.Options = opts
+	}
+
+	writeMarkdownSections(w, help, !conf.EnvDisable, !conf.FileDisable)
+	return nil
+}
+
+// roffFlag formats the flags of the option for the man page, with the flags
+// in bold and the value in italics.
+func roffFlag(opt *HelpOption) string {
+	flag := `\fB\-\-` + roffEscape(opt.FullID) + `\fR`
+	if opt.Short != "" {
+		flag = `\fB\-` + roffEscape(opt.Short) + `\fR, ` + flag
+	}
+	if opt.IsMap {
+		flag += `\fB.\fR\fIkey\fR \fIvalue\fR`
+	} else if opt.VarName != "" {
+		flag += ` \fI` + roffEscape(opt.VarName) + `\fR`
+	}
+	return flag
+}
+
+// roffEscape escapes text for roff.  Backslashes and dashes are escaped and
+// lines are prevented from starting with a control character.
+func roffEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
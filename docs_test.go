@@ -0,0 +1,135 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type docsConfig struct {
+	Verbose  bool   `short:"v" desc:"print more"`
+	Name     string `default:"app" desc:"the name"`
+	Database struct {
+		Host string `desc:"the host"`
+	} `desc:"Database options"`
+	Labels map[string]interface{} `desc:"extra labels"`
+	Secret string                 `opts:"hidden"`
+}
+
+func TestWriteManPage(t *testing.T) {
+	config := docsConfig{}
+	var buf bytes.Buffer
+	require.NoError(t, WriteManPage(&buf, &config, Conf{
+		EnvPrefix:           "APP_",
+		FileDefaultFilename: "/etc/app.yaml",
+	}, ManPage{Name: "app", Description: "does things", Date: "2019-02-01"}))
+
+	assert.Equal(t, `.TH APP 1 "2019-02-01" "" ""
+.SH NAME
+app \- does things
+.SH SYNOPSIS
+.B app
+[\fIOPTIONS\fR]
+.SH OPTIONS
+.TP
+\fB\-v\fR, \fB\-\-verbose\fR
+print more
+.TP
+\fB\-\-name\fR \fIstring\fR
+the name (default "app")
+.TP
+\fB\-\-labels\fR\fB.\fR\fIkey\fR \fIvalue\fR
+extra labels
+.TP
+\fB\-h\fR, \fB\-\-help\fR
+print this help menu
+.TP
+\fB\-\-help\-all\fR
+print this help menu with all options
+.SS Database options
+.TP
+\fB\-\-database.host\fR \fIstring\fR
+the host
+.SH ENVIRONMENT
+.TP
+.B APP_VERBOSE
+Sets \fB\-\-verbose\fR.
+.TP
+.B APP_NAME
+Sets \fB\-\-name\fR.
+.TP
+.B APP_LABELS_<KEY>
+Sets \fB\-\-labels\fR.
+.TP
+.B APP_DATABASE_HOST
+Sets \fB\-\-database.host\fR.
+.SH FILES
+.TP
+.I /etc/app.yaml
+The config file.
+.PP
+The options can be set in the config file with the following keys:
+.TP
+.B verbose
+Sets \fB\-\-verbose\fR.
+.TP
+.B name
+Sets \fB\-\-name\fR.
+.TP
+.B labels
+Sets \fB\-\-labels\fR.
+.TP
+.B database.host
+Sets \fB\-\-database.host\fR.
+`, buf.String())
+
+	// Without env and file, the sections are left out.
+	buf.Reset()
+	require.NoError(t, WriteManPage(&buf, &config, Conf{
+		EnvDisable:  true,
+		FileDisable: true,
+	}, ManPage{Name: "app"}))
+	assert.NotContains(t, buf.String(), ".SH ENVIRONMENT")
+	assert.NotContains(t, buf.String(), ".SH FILES")
+}
+
+func TestWriteManPage_SecretDefault(t *testing.T) {
+	config := struct {
+		Password string `opts:"secret" default:"changeme"`
+	}{}
+	var buf bytes.Buffer
+	require.NoError(t, WriteManPage(&buf, &config, Conf{}, ManPage{Name: "app"}))
+	assert.Contains(t, buf.String(), "(default <redacted>)")
+	assert.NotContains(t, buf.String(), "changeme")
+}
+
+func TestWriteMarkdownReference(t *testing.T) {
+	config := docsConfig{}
+	var buf bytes.Buffer
+	require.NoError(t, WriteMarkdownReference(&buf, &config, Conf{EnvPrefix: "APP_"}))
+
+	assert.Equal(t, "\n"+
+		"| Flag | Default | Description | Environment | File key |\n"+
+		"| --- | --- | --- | --- | --- |\n"+
+		"| `-v, --verbose` |  | print more | `APP_VERBOSE` | `verbose` |\n"+
+		"| `--name string` | `app` | the name | `APP_NAME` | `name` |\n"+
+		"| `--labels.<key> <value>` |  | extra labels | `APP_LABELS_<KEY>` | `labels` |\n"+
+		"\n"+
+		"## Database options\n"+
+		"\n"+
+		"| Flag | Default | Description | Environment | File key |\n"+
+		"| --- | --- | --- | --- | --- |\n"+
+		"| `--database.host string` |  | the host | `APP_DATABASE_HOST` | `database.host` |\n",
+		buf.String())
+}
+
+func TestRoffEscape(t *testing.T) {
+	assert.Equal(t, `a\-b \ec`, roffEscape(`a-b \c`))
+	assert.Equal(t, `\&.start`, roffEscape(".start"))
+}
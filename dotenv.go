@@ -0,0 +1,203 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// readDotEnvFile reads the variables from the dotenv file.  A missing file
+// gives no variables.
+func readDotEnvFile(s *setup) (map[string]string, error) {
+	content, err := ioutil.ReadFile(s.conf.EnvFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading dotenv file at %v: %v",
+			s.conf.EnvFile, err)
+	}
+
+	vars, err := parseDotEnv(content)
+	if err != nil {
+		return nil, &DecodeError{s.conf.EnvFile, err}
+	}
+	return vars, nil
+}
+
+// parseDotEnvFile reads the dotenv file and sets the config options from the
+// variables it contains.  A missing file is silently ignored.
+func parseDotEnvFile(s *setup) error {
+	vars, err := readDotEnvFile(s)
+	if err != nil || vars == nil {
+		return err
+	}
+
+	return parseEnvVars(s, vars, SourceDotEnv)
+}
+
+// parseDotEnv parses the content of a dotenv file.  Every line has the form
+// KEY=VALUE, optionally preceded by "export".  Lines starting with # are
+// comments.  Values can be
+//  - unquoted: surrounding whitespace and comments starting with " #" are
+//    removed and variables are expanded
+//  - single-quoted: the value is taken literally
+//  - double-quoted: escape sequences like \n are interpreted, variables are
+//    expanded and the value can span multiple lines
+// Variables are written as $VAR or ${VAR} and are looked up in the real
+// environment first and in the preceding lines of the file second.
+func parseDotEnv(content []byte) (map[string]string, error) {
+	vars := make(map[string]string)
+	lookup := func(key string) string {
+		if val, ok := os.LookupEnv(key); ok {
+			return val
+		}
+		return vars[key]
+	}
+
+	lines := strings.Split(strings.Replace(string(content), "\r\n", "\n", -1), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNb := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "export ") {
+			line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		}
+
+		sep := strings.IndexByte(line, '=')
+		if sep < 0 {
+			return nil, fmt.Errorf("line %v: expected KEY=VALUE", lineNb)
+		}
+		key, raw := strings.TrimSpace(line[:sep]), strings.TrimSpace(line[sep+1:])
+		if !isEnvKey(key) {
+			return nil, fmt.Errorf("line %v: invalid variable name '%v'", lineNb, key)
+		}
+
+		var value, rest string
+		switch {
+		case strings.HasPrefix(raw, "'"):
+			end := strings.IndexByte(raw[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("line %v: unterminated quoted value", lineNb)
+			}
+			value, rest = raw[1:end+1], raw[end+2:]
+
+		case strings.HasPrefix(raw, `"`):
+			// Double-quoted values can continue on the next lines.
+			end := closingQuote(raw)
+			for end < 0 && i+1 < len(lines) {
+				i++
+				raw += "\n" + lines[i]
+				end = closingQuote(raw)
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("line %v: unterminated quoted value", lineNb)
+			}
+			value, rest = expandDotEnvValue(raw[1:end], true, lookup), raw[end+1:]
+
+		default:
+			if idx := strings.Index(raw, " #"); idx >= 0 {
+				raw = strings.TrimSpace(raw[:idx])
+			}
+			value = expandDotEnvValue(raw, false, lookup)
+		}
+
+		rest = strings.TrimSpace(rest)
+		if rest != "" && rest[0] != '#' {
+			return nil, fmt.Errorf("line %v: unexpected content after quoted "+
+				"value: %v", lineNb, rest)
+		}
+
+		vars[key] = value
+	}
+
+	return vars, nil
+}
+
+// isEnvKey returns whether key is a valid environment variable name.
+func isEnvKey(key string) bool {
+	for i, c := range key {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return key != ""
+}
+
+// closingQuote returns the index of the unescaped double quote that closes
+// the double-quoted string at the start of s, or -1 if there is none.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// expandDotEnvValue expands the variables in a dotenv value using lookup.
+// If escapes is set, backslash escape sequences are interpreted as well.
+func expandDotEnvValue(s string, escapes bool, lookup func(string) string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if escapes && c == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				// Includes \\, \" and \$.
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+
+		if c != '$' || i+1 >= len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		if s[i+1] == '{' {
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteString(lookup(s[i+2 : i+end]))
+			i += end
+			continue
+		}
+
+		end := i + 1
+		for end < len(s) && (s[end] == '_' || s[end] >= 'a' && s[end] <= 'z' ||
+			s[end] >= 'A' && s[end] <= 'Z' || end > i+1 && s[end] >= '0' && s[end] <= '9') {
+			end++
+		}
+		if end == i+1 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteString(lookup(s[i+1 : end]))
+		i = end - 1
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	setOS(nil, map[string]string{"HOME": "/home/me"})
+
+	vars, err := parseDotEnv([]byte(`# comment
+PLAIN=value # trailing comment
+export EXPORTED = exported
+SINGLE='literal $HOME #no comment'
+DOUBLE="line1\nline2 \"quoted\" \$HOME"
+MULTI="first
+second"
+DATA_DIR=${HOME}/data
+NESTED="$DATA_DIR/sub"
+EMPTY=
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"PLAIN":    "value",
+		"EXPORTED": "exported",
+		"SINGLE":   "literal $HOME #no comment",
+		"DOUBLE":   "line1\nline2 \"quoted\" $HOME",
+		"MULTI":    "first\nsecond",
+		"DATA_DIR": "/home/me/data",
+		"NESTED":   "/home/me/data/sub",
+		"EMPTY":    "",
+	}, vars)
+}
+
+func TestParseDotEnv_Invalid(t *testing.T) {
+	for _, content := range []string{
+		"NOVALUE\n",
+		"1KEY=value\n",
+		"KEY='unterminated\n",
+		"KEY=\"unterminated\n",
+		"KEY=\"quoted\" trailing\n",
+	} {
+		_, err := parseDotEnv([]byte(content))
+		assert.Error(t, err, content)
+	}
+}
+
+func TestLoad_DotEnv(t *testing.T) {
+	file, err := ioutil.TempFile("", "gonfig")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("APP_HOST=fromdotenv\n" +
+		"APP_PORT=1\n" +
+		"APP_LABELS_ZONE=a\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	setOS(nil, map[string]string{"APP_PORT": "2"})
+
+	config := struct {
+		Host   string
+		Port   int
+		Labels map[string]interface{}
+	}{}
+	require.NoError(t, Load(&config, Conf{
+		FileDisable: true,
+		FlagDisable: true,
+		EnvPrefix:   "APP_",
+		EnvFile:     file.Name(),
+	}))
+	assert.Equal(t, "fromdotenv", config.Host)
+	assert.Equal(t, 2, config.Port)
+	assert.Equal(t, "a", config.Labels["zone"])
+
+	// A missing dotenv file is ignored.
+	require.NoError(t, Load(&config, Conf{
+		FileDisable: true,
+		FlagDisable: true,
+		EnvFile:     "/doesntexist.env",
+	}))
+}
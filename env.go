@@ -5,8 +5,9 @@
 package gonfig
 
 import (
-	"fmt"
 	"os"
+	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -21,9 +22,35 @@ func makeEnvKey(prefix string, fullID []string) string {
 	return key
 }
 
+// environ returns all environment variables as a map.
+func environ() map[string]string {
+	vars := make(map[string]string)
+	for _, env := range os.Environ() {
+		split := strings.SplitN(env, "=", 2)
+		if len(split) == 2 {
+			vars[split[0]] = split[1]
+		}
+	}
+	return vars
+}
+
 // parseEnv parses the environment variables for all config options
 // and writes the values that have been found in place.
 func parseEnv(s *setup) error {
+	return parseEnvVars(s, environ(), SourceEnv)
+}
+
+// parseEnvVars parses the given environment variables for all config options
+// and writes the values that have been found in place.
+func parseEnvVars(s *setup, vars map[string]string, source Source) error {
+	vars = resolveEnvAliases(s, vars)
+
+	var errs LoadErrors
+	if s.conf.EnvStrict && s.conf.EnvPrefix != "" {
+		errs = appendError(errs, checkUnknownEnv(s, vars, source))
+	}
+
+	interpolateEnv := s.conf.Interpolate && s.conf.InterpolateEnv
 	for _, opt := range s.allOpts {
 		if opt.isParent {
 			continue
@@ -34,38 +61,146 @@ func parseEnv(s *setup) error {
 		if opt.isMap {
 			// An exception for maps, we need to look for all prefixed vars.
 			pref := envKey + "_"
-			for _, env := range os.Environ() {
-				split := strings.SplitN(env, "=", 2)
-				key, value := split[0], split[1]
+			for key, value := range vars {
 				if strings.HasPrefix(key, pref) {
 					mapKey := strings.ToLower(strings.TrimPrefix(key, pref))
-					if err := setSimpleMapValue(opt.value, mapKey, value); err != nil {
-						return fmt.Errorf(
-							"error parsing map value '%v' for config var %v: %v",
-							value, opt.fullID(), err)
+					if interpolateEnv {
+						var err error
+						if value, err = interpolate(s, value, []string{opt.fullID()}); err != nil {
+							errs = appendError(errs, &OptionError{
+								OptionID: opt.fullID(),
+								Source:   source,
+								Err:      err,
+							})
+							continue
+						}
+					}
+					err := setOptionValue(opt, source, func(v reflect.Value) error {
+						return setSimpleMapValue(v, mapKey, value)
+					})
+					if err != nil {
+						errs = appendError(errs,
+							newParseError(opt, source, value, err))
 					}
 				}
 			}
 			continue
 		}
 
-		value, set := os.LookupEnv(envKey)
+		value, set := vars[envKey]
 		if !set {
 			continue
 		}
 
-		if err := setValueByString(opt.value, value); err != nil {
-			return fmt.Errorf("failed to set option '%v' with value '%v': %v",
-				opt.fullID(), value, err)
+		if interpolateEnv {
+			var err error
+			if value, err = interpolate(s, value, []string{opt.fullID()}); err != nil {
+				errs = appendError(errs, &OptionError{
+					OptionID: opt.fullID(),
+					Source:   source,
+					Err:      err,
+				})
+				continue
+			}
+		}
+
+		err := setOptionValue(opt, source, func(v reflect.Value) error {
+			return setValueByString(v, value)
+		})
+		if err != nil {
+			errs = appendError(errs, newParseError(opt, source, value, err))
+		} else if err := resolveOptionPaths(opt, "", true); err != nil {
+			errs = appendError(errs, &OptionError{
+				OptionID: opt.fullID(),
+				Source:   source,
+				Err:      err,
+			})
 		}
 	}
 
-	return nil
+	return errs.errorOrNil()
 }
 
-// lookupConfigFileEnv looks for the config file in the environment variables.
-func lookupConfigFileEnv(s *setup, configOpt *option) (string, error) {
-	val, found := os.LookupEnv(makeEnvKey(s.conf.EnvPrefix, configOpt.fullIDParts))
+// checkUnknownEnv returns an error if there are environment variables that
+// start with the env prefix but that do not correspond to any config option.
+// Every unknown variable is listed with the closest known variable names as
+// suggestions.
+func checkUnknownEnv(s *setup, vars map[string]string, source Source) error {
+	var known, mapPrefixes []string
+	for _, opt := range s.allOpts {
+		if opt.isParent {
+			continue
+		}
+		envKey := makeEnvKey(s.conf.EnvPrefix, opt.fullIDParts)
+		if opt.isMap {
+			mapPrefixes = append(mapPrefixes, envKey+"_")
+		}
+		known = append(known, envKey)
+	}
+
+	var unknown []string
+vars:
+	for key := range vars {
+		if !strings.HasPrefix(key, s.conf.EnvPrefix) {
+			continue
+		}
+		for _, k := range known {
+			if key == k {
+				continue vars
+			}
+		}
+		for _, pref := range mapPrefixes {
+			if strings.HasPrefix(key, pref) {
+				continue vars
+			}
+		}
+		unknown = append(unknown, key)
+	}
+
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknown)
+	location := s.conf.EnvPrefix
+	if source == SourceDotEnv {
+		location = s.conf.EnvFile
+	}
+	err := &UnknownKeyError{
+		Source:      source,
+		Location:    location,
+		Keys:        unknown,
+		Suggestions: make(map[string][]string),
+	}
+	for _, key := range unknown {
+		if suggs := suggestions(key, known); len(suggs) > 0 {
+			err.Suggestions[key] = suggs
+		}
+	}
+	return err
+}
+
+// lookupOptionEnv looks for the value of the option in the environment
+// variables.  It is used for options that are needed before all sources are
+// parsed, like the config file.
+func lookupOptionEnv(s *setup, opt *option) (string, error) {
+	// The variables from the dotenv file are used as well, with the real
+	// environment variables taking precedence.
+	vars := make(map[string]string)
+	if s.conf.EnvFile != "" {
+		// Errors in the dotenv file are reported when it is parsed for all
+		// options.
+		dotEnv, _ := readDotEnvFile(s)
+		for key, val := range dotEnv {
+			vars[key] = val
+		}
+	}
+	for key, val := range environ() {
+		vars[key] = val
+	}
+
+	vars = resolveEnvAliases(s, vars)
+	val, found := vars[makeEnvKey(s.conf.EnvPrefix, opt.fullIDParts)]
 	if !found {
 		return "", nil
 	}
@@ -7,7 +7,10 @@ package gonfig
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 )
 
 // parseError returns a nicely formatted error indicating that we failed to
@@ -26,3 +29,320 @@ func convertibleError(v reflect.Value, t reflect.Type) error {
 	return fmt.Errorf(
 		"incompatible type: %v not convertible to %v", v.Type(), t)
 }
+
+// didYouMean formats the suggestions for an unknown name.  It returns an empty
+// string if there are no suggestions.
+func didYouMean(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" (did you mean %v?)", strings.Join(suggestions, " or "))
+}
+
+// ParseError is the error returned when a value provided for a config option
+// could not be converted into the type of the option.
+type ParseError struct {
+	// OptionID is the full ID of the option, with all its parents' IDs joined
+	// by dots.
+	OptionID string
+	// Source is the source from which the invalid value was loaded.
+	Source Source
+	// Value is a string representation of the invalid value.
+	Value string
+	// Type is the type of the option.
+	Type reflect.Type
+	// Position is the location of the value in the config file.  It is only
+	// set for values from a config file if the location is known.
+	Position Position
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%voption '%v' from %v: invalid value '%v' for type %v: %v",
+		positionPrefix(e.Position), e.OptionID, e.Source, e.Value, e.Type, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// positionPrefix formats the position to prefix an error message, or returns
+// an empty string if the position is unknown.
+func positionPrefix(pos Position) string {
+	if !pos.IsValid() {
+		return ""
+	}
+	return pos.String() + ": "
+}
+
+// newParseError creates a new ParseError for a value that failed to load into
+// the given option.
+func newParseError(opt *option, source Source, value interface{}, err error) *ParseError {
+	return &ParseError{
+		OptionID: opt.fullID(),
+		Source:   source,
+		Value:    fmt.Sprintf("%v", value),
+		Type:     opt.value.Type(),
+		Err:      err,
+	}
+}
+
+// FlagSyntaxError is returned when the command line arguments can not be
+// interpreted as flags.
+type FlagSyntaxError struct {
+	// Arg is the argument that could not be interpreted.
+	Arg string
+}
+
+// Error implements the error interface.
+func (e *FlagSyntaxError) Error() string {
+	return fmt.Sprintf("unexpected word while parsing flags: '%v'", e.Arg)
+}
+
+// UnknownFlagError is returned when command line flags are provided that do
+// not correspond to any config option.
+type UnknownFlagError struct {
+	// Flags holds all unknown flags in sorted order as they were written on
+	// the command line, like "--flag" or "-f".
+	Flags []string
+	// Suggestions holds for every unknown flag the known flags that are most
+	// similar to it.
+	Suggestions map[string][]string
+}
+
+// Error implements the error interface.
+func (e *UnknownFlagError) Error() string {
+	descs := make([]string, len(e.Flags))
+	for i, flag := range e.Flags {
+		descs[i] = flag + didYouMean(e.Suggestions[flag])
+	}
+
+	if len(descs) == 1 {
+		return fmt.Sprintf("unknown flag: %v", descs[0])
+	}
+	return fmt.Sprintf("unknown flags: %v", strings.Join(descs, ", "))
+}
+
+// UnknownKeyError is returned in strict mode when a config file or the
+// environment contains keys that do not correspond to any config option.
+type UnknownKeyError struct {
+	// Source is the source in which the unknown keys were found.
+	Source Source
+	// Location is the path of the file for SourceFile and SourceDotEnv and
+	// the env prefix for SourceEnv.
+	Location string
+	// Keys holds all unknown keys in sorted order.  For config files, nested
+	// keys are joined by dots.  For the environment, these are the names of
+	// the variables.
+	Keys []string
+	// Suggestions holds for every unknown key the known keys that are most
+	// similar to it.
+	Suggestions map[string][]string
+	// Positions holds the location of the unknown keys for which it is known.
+	// The file of a key can differ from Location if it comes from an included
+	// file, a file in Conf.FileDirectory or a profile overlay file.
+	Positions map[string]Position
+}
+
+// Error implements the error interface.
+func (e *UnknownKeyError) Error() string {
+	descs := make([]string, len(e.Keys))
+	for i, key := range e.Keys {
+		descs[i] = key
+		if pos, ok := e.Positions[key]; ok && pos.IsValid() {
+			descs[i] += fmt.Sprintf(" (%v)", pos)
+		}
+		descs[i] += didYouMean(e.Suggestions[key])
+	}
+
+	if e.Source == SourceEnv {
+		return fmt.Sprintf("unknown environment variables with prefix %v: %v",
+			e.Location, strings.Join(descs, ", "))
+	}
+	return fmt.Sprintf("unknown keys in %v at %v: %v",
+		e.Source, e.Location, strings.Join(descs, ", "))
+}
+
+// FileNotFoundError is returned when the config file that was explicitly
+// provided by the user does not exist, or when the default config file is
+// required but could not be found.
+type FileNotFoundError struct {
+	// Path is the absolute path of the config file.
+	Path string
+	// Searched holds all the locations where the default config file was
+	// looked for.  It is empty for config files provided by the user.
+	Searched []string
+}
+
+// Error implements the error interface.
+func (e *FileNotFoundError) Error() string {
+	if len(e.Searched) > 1 {
+		return fmt.Sprintf("config file %v not found in any of: %v",
+			filepath.Base(e.Path), strings.Join(e.Searched, ", "))
+	}
+	return fmt.Sprintf("config file at %v does not exist", e.Path)
+}
+
+// Unwrap returns os.ErrNotExist so that errors.Is(err, os.ErrNotExist) holds.
+func (e *FileNotFoundError) Unwrap() error {
+	return os.ErrNotExist
+}
+
+// DecodeError is returned when the content of the config file could not be
+// decoded.
+type DecodeError struct {
+	// Path is the path of the config file.
+	Path string
+	// Err is the error returned by the decoder.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to parse file at %v: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Source identifies a source of config values.
+type Source string
+
+const ( // The sources config values can be loaded from.
+	SourceFile   Source = "config file"
+	SourceMap    Source = "map"
+	SourceDotEnv Source = "dotenv file"
+	SourceEnv    Source = "environment"
+	SourceFlag   Source = "command line flags"
+)
+
+// OptionError is the error returned when the value for a single config option
+// could not be loaded.
+type OptionError struct {
+	// OptionID is the full ID of the option, with all its parents' IDs joined
+	// by dots.
+	OptionID string
+	// Source is the source from which the invalid value was loaded.
+	Source Source
+	// Position is the location of the value in the config file.  It is only
+	// set for values from a config file if the location is known.
+	Position Position
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("%voption '%v' from %v: %v",
+		positionPrefix(e.Position), e.OptionID, e.Source, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *OptionError) Unwrap() error {
+	return e.Err
+}
+
+// ConstraintError is the error returned when a constraint from
+// Conf.Constraints is violated.
+type ConstraintError struct {
+	// Constraint is the violated constraint.
+	Constraint Constraint
+	// Set are the full IDs of the options of the constraint that are set.
+	Set []string
+	// Sources are the sources that set the options in Set, in the same order.
+	Sources []Source
+	// Missing are the full IDs of the options of the constraint that are not
+	// set.
+	Missing []string
+}
+
+// Error implements the error interface.
+func (e *ConstraintError) Error() string {
+	set := make([]string, len(e.Set))
+	for i := range e.Set {
+		set[i] = fmt.Sprintf("'%v' (from %v)", e.Set[i], e.Sources[i])
+	}
+	missing := make([]string, len(e.Missing))
+	for i := range e.Missing {
+		missing[i] = fmt.Sprintf("'%v'", e.Missing[i])
+	}
+	all := make([]string, len(e.Constraint.Options))
+	for i := range e.Constraint.Options {
+		all[i] = fmt.Sprintf("'%v'", e.Constraint.Options[i])
+	}
+
+	switch {
+	case e.Constraint.Kind == ConstraintExactlyOne && len(e.Set) == 0:
+		return fmt.Sprintf("exactly one of the options %v is required", joinAnd(all))
+	case e.Constraint.Kind == ConstraintExclusive ||
+		e.Constraint.Kind == ConstraintExactlyOne:
+		return fmt.Sprintf("options %v are mutually exclusive", joinAnd(set))
+	case e.Constraint.Kind == ConstraintTogether:
+		verb := "is"
+		if len(set) > 1 {
+			verb = "are"
+		}
+		return fmt.Sprintf("options %v must be set together, but only %v %v set",
+			joinAnd(all), joinAnd(set), verb)
+	default:
+		return fmt.Sprintf("option %v requires %v", set[0], joinAnd(missing))
+	}
+}
+
+// LoadErrors holds all errors that occurred while loading the configuration.
+// gonfig does not stop at the first invalid value, but goes through all
+// options and all sources so that the user can fix all problems at once.
+//
+// The individual errors can be inspected with errors.Is and errors.As.
+type LoadErrors []error
+
+// Error implements the error interface.
+func (e LoadErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = "\n  - " + err.Error()
+	}
+	return fmt.Sprintf("%v errors occurred while loading config:%v",
+		len(e), strings.Join(msgs, ""))
+}
+
+// Unwrap returns the individual errors.
+func (e LoadErrors) Unwrap() []error {
+	return e
+}
+
+// appendError adds err to errs, flattening it if it is a LoadErrors itself.
+// Nil errors are ignored.
+func appendError(errs LoadErrors, err error) LoadErrors {
+	if err == nil {
+		return errs
+	}
+	if multi, ok := err.(LoadErrors); ok {
+		return append(errs, multi...)
+	}
+	return append(errs, err)
+}
+
+// errorOrNil returns nil if there are no errors and errs otherwise.  If the
+// version was requested, ErrVersion is returned on its own.
+func (e LoadErrors) errorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	for _, err := range e {
+		if err == ErrVersion {
+			return ErrVersion
+		}
+	}
+	return e
+}
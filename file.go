@@ -8,13 +8,16 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path"
+	"path/filepath"
 	"reflect"
+	"sort"
 )
 
 // parseMapOpts parses options from a map[string]interface{}.  This is used
 // for configuration file encodings that can decode to such a map.
-func parseMapOpts(j map[string]interface{}, opts []*option) error {
+// All options are tried and all errors are returned together.
+func parseMapOpts(j map[string]interface{}, opts []*option, source Source) error {
+	var errs LoadErrors
 	for _, opt := range opts {
 		val, set := j[opt.id]
 		if !set {
@@ -23,54 +26,166 @@ func parseMapOpts(j map[string]interface{}, opts []*option) error {
 
 		if opt.isParent {
 			if casted, ok := val.(map[string]interface{}); ok {
-				if err := parseMapOpts(casted, opt.subOpts); err != nil {
-					return err
-				}
+				errs = appendError(errs, parseMapOpts(casted, opt.subOpts, source))
 			} else {
-				return fmt.Errorf("error parsing config file: "+
-					"value of type %v given for composite config var %v",
-					reflect.TypeOf(val), opt.fullID())
+				errs = appendError(errs, &OptionError{
+					OptionID: opt.fullID(),
+					Source:   source,
+					Err: fmt.Errorf("value of type %v given for composite "+
+						"config var", reflect.TypeOf(val)),
+				})
 			}
 		} else {
-			if err := setValue(opt.value, reflect.ValueOf(val)); err != nil {
-				return fmt.Errorf("failed to set option '%v': %v",
-					opt.fullID(), err)
+			err := setOptionValue(opt, source, func(v reflect.Value) error {
+				return setValue(v, reflect.ValueOf(val))
+			})
+			if err != nil {
+				errs = appendError(errs, newParseError(opt, source, val, err))
 			}
 		}
 	}
 
-	return nil
+	return errs.errorOrNil()
+}
+
+// unknownMapKeys returns the paths of all the keys in m that do not correspond
+// to any of the given options, recursing into nested structs.  Paths are
+// joined by dots and prefixed with the given prefix.  For each unknown key,
+// the closest known keys are added to suggs.
+func unknownMapKeys(m map[string]interface{}, opts []*option, prefix string,
+	suggs map[string][]string) []string {
+	var unknown []string
+keys:
+	for key, val := range m {
+		for _, opt := range opts {
+			if opt.id != key {
+				continue
+			}
+			if casted, ok := val.(map[string]interface{}); ok && opt.isParent {
+				unknown = append(unknown,
+					unknownMapKeys(casted, opt.subOpts, prefix+key+".", suggs)...)
+			}
+			continue keys
+		}
+
+		unknown = append(unknown, prefix+key)
+		if similar := suggestions(key, optionIDs(opts)); len(similar) > 0 {
+			for i := range similar {
+				similar[i] = prefix + similar[i]
+			}
+			suggs[prefix+key] = similar
+		}
+	}
+
+	return unknown
 }
 
 // parseFileContent parses the config file given its content.
 func parseFileContent(s *setup, content []byte) error {
-	decoder := s.conf.FileDecoder
-	if decoder == nil {
-		// Look for the config file extension to determine the encoding.
-		switch path.Ext(s.configFilePath) {
-		case "json":
-			decoder = DecoderJSON
-		case "toml":
-			decoder = DecoderTOML
-		case "yaml", "yml":
-			decoder = DecoderYAML
-		default:
-			decoder = DecoderTryAll
+	m, positions, err := decodeFile(s, s.configFilePath, content, nil, true)
+	if err != nil {
+		return err
+	}
+
+	return parseFileMap(s, m, positions)
+}
+
+// parseFileMap parses the options from the decoded config file, after
+// merging in the overlay file for the profile and the files from the config
+// directory and applying the profile section.
+func parseFileMap(s *setup, m map[string]interface{}, positions map[string]Position) error {
+	if s.conf.ProfileVariable != "" {
+		pm, ppositions, err := decodeProfileFile(s)
+		if err != nil {
+			return err
 		}
+		mergeMaps(m, pm)
+		mergePositions(positions, ppositions)
 	}
 
-	m, err := decoder(content)
-	if err != nil {
-		return fmt.Errorf("failed to parse file at %v: %v",
-			s.configFilePath, err)
+	if s.conf.FileDirectory != "" {
+		dir := resolvePath(filepath.Dir(s.configFilePath), s.conf.FileDirectory)
+		dm, dpositions, err := decodeDirectory(s, dir)
+		if err != nil {
+			return err
+		}
+		mergeMaps(m, dm)
+		mergePositions(positions, dpositions)
+	}
+
+	if s.conf.ProfileVariable != "" {
+		if err := applyProfile(s, m, positions); err != nil {
+			return err
+		}
+	}
+
+	resolveMapAliases(s, m, positions, SourceFile)
+
+	var errs LoadErrors
+	if s.conf.FileStrict {
+		suggs := make(map[string][]string)
+		if unknown := unknownMapKeys(m, s.opts, "", suggs); len(unknown) > 0 {
+			sort.Strings(unknown)
+			errs = appendError(errs, &UnknownKeyError{
+				Source:      SourceFile,
+				Location:    s.configFilePath,
+				Keys:        unknown,
+				Suggestions: suggs,
+				Positions:   positions,
+			})
+		}
+	}
+
+	var optErrs LoadErrors
+	if s.conf.Interpolate {
+		addInterpolationValues(s, m, s.opts)
+		var err error
+		m, err = interpolateMap(s, m, s.opts, SourceFile)
+		optErrs = appendError(optErrs, err)
 	}
 
 	// Parse the map for the options.
-	if err := parseMapOpts(m, s.opts); err != nil {
-		return fmt.Errorf("error loading config vars from config file: %v", err)
+	optErrs = appendError(optErrs, parseMapOpts(m, s.opts, SourceFile))
+
+	// Relative paths in the config file are relative to its directory.
+	optErrs = appendError(optErrs, resolveMapPaths(m, s.opts,
+		filepath.Dir(s.configFilePath), SourceFile))
+
+	if s.conf.Interpolate {
+		optErrs = appendError(optErrs, reapplyDefaults(s, SourceFile))
+	}
+
+	// Add the location of the invalid values to the errors.
+	for _, err := range optErrs {
+		switch err := err.(type) {
+		case *ParseError:
+			err.Position = positions[err.OptionID]
+		case *OptionError:
+			err.Position = positions[err.OptionID]
+		}
+	}
+	errs = appendError(errs, optErrs.errorOrNil())
+
+	return errs.errorOrNil()
+}
+
+// filePositions returns the positions of the keys in the config file, or nil
+// if they can't be determined.
+func filePositions(path string, positionsFn FilePositionsFn, content []byte) map[string]Position {
+	if positionsFn == nil {
+		return nil
 	}
 
-	return nil
+	positions, err := positionsFn(content)
+	if err != nil {
+		// The positions are only used to improve error messages.
+		return nil
+	}
+	for key, pos := range positions {
+		pos.Filename = path
+		positions[key] = pos
+	}
+	return positions
 }
 
 // parseFile parses the config file for all config options by delegating
@@ -81,8 +196,16 @@ func parseFile(s *setup) error {
 		// the default config file, but we escalate if the user provided
 		// the config file explicitely.
 		if s.customConfigFile {
-			return fmt.Errorf(
-				"config file at %v does not exist", s.configFilePath)
+			return &FileNotFoundError{Path: s.configFilePath}
+		} else if s.conf.FileRequired {
+			return &FileNotFoundError{
+				Path:     s.configFilePath,
+				Searched: s.configFileSearched,
+			}
+		} else if s.conf.FileDirectory != "" {
+			// The config directory can be used without a config file.
+			return parseFileMap(s, make(map[string]interface{}),
+				make(map[string]Position))
 		} else {
 			return nil
 		}
@@ -93,6 +216,9 @@ func parseFile(s *setup) error {
 		return fmt.Errorf(
 			"error reading config file at %v: %v", s.configFilePath, err)
 	}
+	if s.conf.FileUsed != nil {
+		*s.conf.FileUsed = s.configFilePath
+	}
 
 	return parseFileContent(s, content)
 }
@@ -5,15 +5,19 @@
 package gonfig
 
 import (
+	"errors"
 	"io/ioutil"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestParseFile_FileNotExist_Default(t *testing.T) {
 	require.NoError(t, parseFile(&setup{
 		configFilePath: "/doesntexist.conf",
+		conf:           &Conf{},
 	}))
 }
 
@@ -104,3 +108,63 @@ func TestParseFile_MultiDecoder(t *testing.T) {
 		},
 	}))
 }
+
+func TestParseFileContent_Strict(t *testing.T) {
+	config := struct {
+		Listen   string
+		Database struct {
+			Password string
+		}
+		Extra map[string]interface{}
+	}{}
+	s := &setup{
+		configFilePath: "/app.yaml",
+		conf: &Conf{
+			FileDecoder: DecoderYAML,
+			FileStrict:  true,
+		},
+	}
+	require.NoError(t, inspectConfigStructure(s, &config))
+
+	err := parseFileContent(s, []byte("listen: x\n"+
+		"database:\n"+
+		"  pasword: secret\n"+
+		"extra:\n"+
+		"  anything: goes\n"+
+		"obsolete: true\n"))
+	require.Error(t, err)
+	assert.Equal(t, "unknown keys in config file at /app.yaml: "+
+		"database.pasword (/app.yaml:3:3) (did you mean database.password?), "+
+		"obsolete (/app.yaml:6:1)",
+		err.Error())
+
+	s.conf.FileStrict = false
+	require.NoError(t, parseFileContent(s, []byte("obsolete: true\n")))
+}
+
+func TestParseFileContent_Positions(t *testing.T) {
+	config := struct {
+		Name     string
+		Database struct {
+			Port int
+		}
+	}{}
+	s := &setup{
+		configFilePath: "/app.json",
+		conf: &Conf{
+			FileDecoder: DecoderJSON,
+		},
+	}
+	require.NoError(t, inspectConfigStructure(s, &config))
+
+	err := parseFileContent(s, []byte(`{
+	"name": "x",
+	"database": {
+		"port": "abc"
+	}
+}`))
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, Position{"/app.json", 4, 3}, parseErr.Position)
+	assert.True(t, strings.HasPrefix(err.Error(), "/app.json:4:3: "))
+}
@@ -5,12 +5,18 @@
 package gonfig
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/pelletier/go-toml"
 	yaml "gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
 )
 
 // FileDecoderFn represents a method that translates the content of a config file
@@ -23,6 +29,39 @@ import (
 // - a map[string]interface{}
 type FileDecoderFn func(content []byte) (map[string]interface{}, error)
 
+// Position is the location of a key inside a config file.
+type Position struct {
+	Filename string // the path of the file, if known
+	Line     int    // line number, starting at 1
+	Column   int    // column number, starting at 1, or 0 if unknown
+}
+
+// IsValid returns whether the position contains location information.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position in the form "file:line:column".
+func (p Position) String() string {
+	s := fmt.Sprintf("%v:%v", p.Filename, p.Line)
+	if p.Filename == "" {
+		s = fmt.Sprintf("line %v", p.Line)
+	}
+	if p.Column > 0 {
+		s += fmt.Sprintf(":%v", p.Column)
+	}
+	return s
+}
+
+// FilePositionsFn is an optional companion of a FileDecoderFn that locates
+// the keys in the content of a config file.  It returns the position of every
+// key it can find, with the keys of nested maps joined to their parents' by
+// dots, like "database.password".
+//
+// When gonfig knows the positions of the keys, errors about values from the
+// config file will mention their location.
+type FilePositionsFn func(content []byte) (map[string]Position, error)
+
 // DecoderJSON is the JSON decoding function for config files.
 var DecoderJSON FileDecoderFn = func(c []byte) (map[string]interface{}, error) {
 	var m map[string]interface{}
@@ -54,6 +93,310 @@ var DecoderYAML FileDecoderFn = func(c []byte) (map[string]interface{}, error) {
 	return m, nil
 }
 
+// PositionsJSON is the FilePositionsFn for JSON config files.
+var PositionsJSON FilePositionsFn = func(c []byte) (map[string]Position, error) {
+	offsets, err := jsonKeyOffsets(c)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing JSON config file: %v", err)
+	}
+
+	positions := make(map[string]Position, len(offsets))
+	for key, offset := range offsets {
+		positions[key] = offsetPosition(c, offset)
+	}
+	return positions, nil
+}
+
+// jsonKeyOffsets returns the byte offsets of the keys in the JSON content.
+func jsonKeyOffsets(c []byte) (map[string]int, error) {
+	// A stack of the objects and arrays we are in.  We only keep track of the
+	// positions of keys in nested objects, not inside arrays.
+	type container struct {
+		object    bool
+		tracked   bool
+		prefix    string
+		key       string
+		expectKey bool
+	}
+	var stack []*container
+
+	// valueDone should be called after every value that ended.
+	valueDone := func() {
+		if len(stack) > 0 && stack[len(stack)-1].object {
+			stack[len(stack)-1].expectKey = true
+		}
+	}
+
+	offsets := make(map[string]int)
+	dec := json.NewDecoder(bytes.NewReader(c))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var top *container
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		if top != nil && top.object && top.expectKey {
+			if key, ok := tok.(string); ok {
+				if top.tracked {
+					offsets[top.prefix+key] = jsonStringStart(c, int(dec.InputOffset()))
+				}
+				top.key = key
+				top.expectKey = false
+				continue
+			}
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			child := &container{object: true, tracked: true, expectKey: true}
+			if top != nil {
+				child.tracked = top.object && top.tracked
+				child.prefix = top.prefix + top.key + "."
+			}
+			stack = append(stack, child)
+
+		case json.Delim('['):
+			stack = append(stack, &container{})
+
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			valueDone()
+
+		default:
+			valueDone()
+		}
+	}
+
+	return offsets, nil
+}
+
+// jsonStringStart returns the offset of the opening quote of the JSON string
+// that ends just before the given offset.
+func jsonStringStart(c []byte, end int) int {
+	for i := end - 2; i >= 0; i-- {
+		if c[i] != '"' {
+			continue
+		}
+		// Count the backslashes before the quote to know if it is escaped.
+		escapes := 0
+		for j := i - 1; j >= 0 && c[j] == '\\'; j-- {
+			escapes++
+		}
+		if escapes%2 == 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+// offsetPosition converts a byte offset in c into a position.
+func offsetPosition(c []byte, offset int) Position {
+	before := c[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(before, '\n')
+	return Position{Line: line, Column: column}
+}
+
+// PositionsTOML is the FilePositionsFn for TOML config files.
+var PositionsTOML FilePositionsFn = func(c []byte) (map[string]Position, error) {
+	tomlTree, err := toml.LoadBytes(c)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing TOML config file: %v", err)
+	}
+
+	positions := make(map[string]Position)
+	var walk func(t *toml.Tree, prefix string)
+	walk = func(t *toml.Tree, prefix string) {
+		for _, key := range t.Keys() {
+			pos := t.GetPositionPath([]string{key})
+			positions[prefix+key] = Position{Line: pos.Line, Column: pos.Col}
+			if sub, ok := t.GetPath([]string{key}).(*toml.Tree); ok {
+				walk(sub, prefix+key+".")
+			}
+		}
+	}
+	walk(tomlTree, "")
+
+	return positions, nil
+}
+
+// PositionsYAML is the FilePositionsFn for YAML config files.
+var PositionsYAML FilePositionsFn = func(c []byte) (map[string]Position, error) {
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(c, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing YAML config file: %v", err)
+	}
+
+	positions := make(map[string]Position)
+	var walk func(n *yaml3.Node, prefix string)
+	walk = func(n *yaml3.Node, prefix string) {
+		if n.Kind != yaml3.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			positions[prefix+key.Value] = Position{
+				Line:   key.Line,
+				Column: key.Column,
+			}
+			walk(value, prefix+key.Value+".")
+		}
+	}
+	if len(doc.Content) > 0 {
+		walk(doc.Content[0], "")
+	}
+
+	return positions, nil
+}
+
+// builtinPositions returns the FilePositionsFn that matches the given decoder
+// if it is one of the single-format decoders provided by gonfig and nil
+// otherwise.
+func builtinPositions(decoder FileDecoderFn) FilePositionsFn {
+	ptr := reflect.ValueOf(decoder).Pointer()
+	switch ptr {
+	case reflect.ValueOf(DecoderJSON).Pointer():
+		return PositionsJSON
+	case reflect.ValueOf(DecoderTOML).Pointer():
+		return PositionsTOML
+	case reflect.ValueOf(DecoderYAML).Pointer():
+		return PositionsYAML
+	case reflect.ValueOf(DecoderINI).Pointer():
+		return PositionsINI
+	case reflect.ValueOf(DecoderHCL).Pointer():
+		return PositionsHCL
+	case reflect.ValueOf(DecoderJSON5).Pointer():
+		return PositionsJSON5
+	case reflect.ValueOf(DecoderProperties).Pointer():
+		return PositionsProperties
+	}
+	return nil
+}
+
+// tryAllFormats pairs the decoders tried by DecoderTryAll, in the same order,
+// with their FilePositionsFn.
+var tryAllFormats = []struct {
+	decoder   FileDecoderFn
+	positions FilePositionsFn
+}{
+	{DecoderYAML, PositionsYAML},
+	{DecoderTOML, PositionsTOML},
+	{DecoderJSON, PositionsJSON},
+}
+
+// positionsTryAll is the FilePositionsFn to use with DecoderTryAll.  It uses
+// the positions of the format whose decoder DecoderTryAll uses.  The positions
+// functions can't be tried on their own, because PositionsYAML also accepts
+// some TOML content, like a single line "name = value".
+var positionsTryAll FilePositionsFn = func(c []byte) (map[string]Position, error) {
+	for _, format := range tryAllFormats {
+		if _, err := format.decoder(c); err == nil {
+			return format.positions(c)
+		}
+	}
+	return nil, errors.New("config file failed to decode")
+}
+
+// decoders holds the decoders registered for each file extension.
+var decoders = struct {
+	sync.RWMutex
+	byExt map[string]FileDecoderFn
+}{
+	byExt: make(map[string]FileDecoderFn),
+}
+
+func init() {
+	RegisterDecoder("json", DecoderJSON)
+	RegisterDecoder("toml", DecoderTOML)
+	RegisterDecoder("yaml", DecoderYAML)
+	RegisterDecoder("yml", DecoderYAML)
+	RegisterDecoder("ini", DecoderINI)
+	RegisterDecoder("conf", DecoderINI)
+	RegisterDecoder("hcl", DecoderHCL)
+	RegisterDecoder("jsonc", DecoderJSONC)
+	RegisterDecoder("json5", DecoderJSON5)
+	RegisterDecoder("properties", DecoderProperties)
+}
+
+// normalizeExt brings a file extension in the form used in the registry:
+// lowercase and without leading dot.
+func normalizeExt(ext string) string {
+	return strings.ToLower(strings.TrimPrefix(ext, "."))
+}
+
+// RegisterDecoder registers the decoder to be used for config files with the
+// given file extension, with or without leading dot.  It is used when no
+// decoder is specified in Conf.FileDecoder.  Registering a decoder for an
+// extension that already has one replaces it.
+//
+// The decoders for the .json, .jsonc, .json5, .toml, .yaml, .yml, .ini, .conf,
+// .hcl and .properties extensions are registered by default.
+func RegisterDecoder(ext string, decoder FileDecoderFn) {
+	decoders.Lock()
+	defer decoders.Unlock()
+
+	if decoder == nil {
+		delete(decoders.byExt, normalizeExt(ext))
+	} else {
+		decoders.byExt[normalizeExt(ext)] = decoder
+	}
+}
+
+// lookupDecoder returns the decoder registered for the extension, or nil.
+func lookupDecoder(ext string) FileDecoderFn {
+	decoders.RLock()
+	defer decoders.RUnlock()
+
+	return decoders.byExt[normalizeExt(ext)]
+}
+
+// sniffDecoder guesses the format of a config file by looking at the start of
+// its content and returns the matching decoder.  It returns nil if the format
+// is not clear.
+func sniffDecoder(c []byte) FileDecoderFn {
+	c = bytes.TrimPrefix(c, []byte("\xef\xbb\xbf")) // UTF-8 byte order mark
+	trimmed := bytes.TrimSpace(c)
+	if bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("//")) ||
+		bytes.HasPrefix(trimmed, []byte("/*")) {
+		// JSON5 is a superset of JSON that also allows comments.
+		return DecoderJSON5
+	}
+	if bytes.HasPrefix(trimmed, []byte("---")) {
+		return DecoderYAML
+	}
+
+	// Both YAML and TOML use # for comments, so we look at the first line that
+	// is not a comment.
+	for _, line := range bytes.Split(trimmed, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			return DecoderTOML // a table header
+		}
+		colon, equals := bytes.IndexByte(line, ':'), bytes.IndexByte(line, '=')
+		switch {
+		case equals > 0 && (colon < 0 || equals < colon):
+			return DecoderTOML
+		case colon > 0 && (equals < 0 || colon < equals):
+			return DecoderYAML
+		}
+		return nil
+	}
+
+	return nil
+}
+
 // NewMultiFileDecoder is a hybrid decoders that will try all the given decoders
 // and return the result of the first one that does not produce an error.
 func NewMultiFileDecoder(decoders []FileDecoderFn) FileDecoderFn {
@@ -0,0 +1,169 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPositionsJSON(t *testing.T) {
+	positions, err := PositionsJSON([]byte(`{
+  "name": "x",
+  "list": [{"ignored": 1}, 2],
+  "nested": {"key": "v", "esc\"aped": true}
+}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Position{
+		"name":             {Line: 2, Column: 3},
+		"list":             {Line: 3, Column: 3},
+		"nested":           {Line: 4, Column: 3},
+		"nested.key":       {Line: 4, Column: 14},
+		"nested.esc\"aped": {Line: 4, Column: 26},
+	}, positions)
+}
+
+func TestPositionsTOML(t *testing.T) {
+	positions, err := PositionsTOML([]byte("name = \"x\"\n" +
+		"\n" +
+		"[nested]\n" +
+		"key = \"v\"\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, positions["name"].Line)
+	assert.Equal(t, 3, positions["nested"].Line)
+	assert.Equal(t, Position{Line: 4, Column: 1}, positions["nested.key"])
+}
+
+func TestPositionsYAML(t *testing.T) {
+	positions, err := PositionsYAML([]byte("name: x\n" +
+		"nested:\n" +
+		"  key: v\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Position{
+		"name":       {Line: 1, Column: 1},
+		"nested":     {Line: 2, Column: 1},
+		"nested.key": {Line: 3, Column: 3},
+	}, positions)
+}
+
+func TestBuiltinPositions(t *testing.T) {
+	assert.NotNil(t, builtinPositions(DecoderJSON))
+	assert.NotNil(t, builtinPositions(DecoderYAML))
+	assert.NotNil(t, builtinPositions(DecoderTOML))
+	assert.Nil(t, builtinPositions(DecoderTryAll))
+	assert.Nil(t, builtinPositions(func(c []byte) (map[string]interface{}, error) {
+		return nil, nil
+	}))
+}
+
+func TestPositionsTryAll(t *testing.T) {
+	// The YAML parser used for the positions accepts this as a plain string,
+	// but the file is decoded as TOML.
+	positions, err := positionsTryAll([]byte("name = \"y\"\n" +
+		"\n" +
+		"port = 1\n"))
+	require.NoError(t, err)
+	assert.Equal(t, Position{Line: 1, Column: 1}, positions["name"])
+	assert.Equal(t, Position{Line: 3, Column: 1}, positions["port"])
+
+	positions, err = positionsTryAll([]byte("name: y\n"))
+	require.NoError(t, err)
+	assert.Equal(t, Position{Line: 1, Column: 1}, positions["name"])
+
+	_, err = positionsTryAll([]byte("name: [\n"))
+	assert.Error(t, err)
+}
+
+func TestPosition_String(t *testing.T) {
+	assert.Equal(t, "app.yaml:3:5", Position{"app.yaml", 3, 5}.String())
+	assert.Equal(t, "app.yaml:3", Position{"app.yaml", 3, 0}.String())
+	assert.Equal(t, "line 3:5", Position{"", 3, 5}.String())
+}
+
+func TestSniffDecoder(t *testing.T) {
+	testCases := []struct {
+		content  string
+		expected FileDecoderFn
+	}{
+		{"  {\"key\": 1}", DecoderJSON5},
+		{"// comment\n{key: 1}", DecoderJSON5},
+		{"---\nkey: 1\n", DecoderYAML},
+		{"# comment\nkey: 1\n", DecoderYAML},
+		{"url: http://host/?a=b\n", DecoderYAML},
+		{"# comment\nkey = 1\n", DecoderTOML},
+		{"url = \"http://host\"\n", DecoderTOML},
+		{"[table]\nkey = 1\n", DecoderTOML},
+		{"# only a comment\n", nil},
+		{"", nil},
+	}
+
+	for _, tc := range testCases {
+		result := sniffDecoder([]byte(tc.content))
+		if tc.expected == nil {
+			assert.Nil(t, result, tc.content)
+		} else {
+			assert.Equal(t, reflect.ValueOf(tc.expected).Pointer(),
+				reflect.ValueOf(result).Pointer(), tc.content)
+		}
+	}
+}
+
+func TestParseFileContent_SniffFallback(t *testing.T) {
+	config := struct {
+		Host string `id:"db:host"`
+		Port int
+	}{}
+
+	// The colon in the quoted key makes the content look like YAML.
+	s := &setup{configFilePath: "/etc/app", conf: &Conf{}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+	require.NoError(t, parseFileContent(s, []byte("\"db:host\" = \"x\"\nport = 1\n")))
+	assert.Equal(t, "x", config.Host)
+	assert.Equal(t, 1, config.Port)
+
+	// The positions are those of the format that was used.
+	s = &setup{configFilePath: "/etc/app", conf: &Conf{}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+	err := parseFileContent(s, []byte("\"db:host\" = \"x\"\nport = \"y\"\n"))
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, Position{"/etc/app", 2, 1}, parseErr.Position)
+
+	// If no format works, the error of the guessed format is returned.
+	s = &setup{configFilePath: "/etc/app", conf: &Conf{}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+	err = parseFileContent(s, []byte("port: [1\n"))
+	var decodeErr *DecodeError
+	require.True(t, errors.As(err, &decodeErr))
+	assert.Contains(t, err.Error(), "error parsing YAML config file")
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	called := false
+	custom := func(c []byte) (map[string]interface{}, error) {
+		called = true
+		return map[string]interface{}{"v": 7}, nil
+	}
+	RegisterDecoder(".Custom", custom)
+
+	config := struct{ V int }{}
+	s := &setup{configFilePath: "/etc/app.custom", conf: &Conf{}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+	require.NoError(t, parseFileContent(s, []byte("anything")))
+	assert.True(t, called)
+	assert.Equal(t, 7, config.V)
+	RegisterDecoder("custom", nil)
+	assert.Nil(t, lookupDecoder(".custom"))
+
+	// The extension of built-in formats is now taken into account.
+	s = &setup{configFilePath: "/etc/app.toml", conf: &Conf{}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+	require.NoError(t, parseFileContent(s, []byte("v = 8\n")))
+	assert.Equal(t, 8, config.V)
+}
@@ -5,8 +5,10 @@
 package gonfig
 
 import (
-	"fmt"
+	"errors"
 	"os"
+	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -24,7 +26,9 @@ func flagFromWord(w string) string {
 }
 
 // parseFlagsToMap parses the given command line flags into a string.
-func parseFlagsToMap(s *setup, args []string) (map[string]string, error) {
+// If handleHelp is set, the help message is printed when the help flag is
+// given.  The version flag is always handled, see handleVersionFlag.
+func parseFlagsToMap(s *setup, args []string, handleHelp bool) (map[string]string, error) {
 	args = args[1:]
 	result := map[string]string{}
 
@@ -32,20 +36,25 @@ func parseFlagsToMap(s *setup, args []string) (map[string]string, error) {
 	for i < len(args) {
 		arg := args[i]
 
-		if !s.conf.HelpDisable && (arg == "--help" || arg == "-h") {
-			printHelpAndExit(s)
+		if handleHelp && !s.conf.HelpDisable && (arg == "--help" || arg == "-h") {
+			printHelpAndExit(s, false)
+		}
+		if handleHelp && !s.conf.HelpDisable && arg == "--help-all" {
+			printHelpAndExit(s, true)
+		}
+		if arg == "--version" && versionEnabled(s) {
+			return nil, handleVersionFlag(s)
 		}
 
 		if arg == "--" {
 			// separator that indicates end of flags
-			return result, nil
+			break
 		}
 
 		parts := strings.SplitN(arg, "=", 2)
 		key := flagFromWord(parts[0])
 		if key == "" {
-			return nil, fmt.Errorf(
-				"unexpected word while parsing flags: '%v'", arg)
+			return nil, &FlagSyntaxError{arg}
 		}
 
 		addValue := func(key, newValue string) {
@@ -75,17 +84,19 @@ func parseFlagsToMap(s *setup, args []string) (map[string]string, error) {
 		i += 2
 	}
 
+	resolveFlagAliases(s, result)
 	return result, nil
 }
 
 // parseFlags parses the command line flags for all config options
 // and writes the values that have been found in place.
 func parseFlags(s *setup) error {
-	flagsMap, err := parseFlagsToMap(s, os.Args)
+	flagsMap, err := parseFlagsToMap(s, os.Args, true)
 	if err != nil {
 		return err
 	}
 
+	var errs LoadErrors
 	for _, opt := range s.allOpts {
 		if opt.isParent {
 			// Parents are skipped, we should only add the children.
@@ -97,10 +108,12 @@ func parseFlags(s *setup) error {
 			for flag, value := range flagsMap {
 				if strings.HasPrefix(flag, opt.fullID()+".") {
 					key := strings.TrimPrefix(flag, opt.fullID()+".")
-					if err := setSimpleMapValue(opt.value, key, value); err != nil {
-						return fmt.Errorf(
-							"error parsing map value '%v' for config var %v: %v",
-							value, opt.fullID(), err)
+					err := setOptionValue(opt, SourceFlag, func(v reflect.Value) error {
+						return setSimpleMapValue(v, key, value)
+					})
+					if err != nil {
+						errs = appendError(errs,
+							newParseError(opt, SourceFlag, value, err))
 					}
 					delete(flagsMap, flag)
 				}
@@ -119,34 +132,110 @@ func parseFlags(s *setup) error {
 		if !fullSet && !shortSet {
 			continue
 		} else if fullSet && shortSet {
-			return fmt.Errorf("flag is set with both short and full form: %v",
-				opt.fullID())
+			errs = appendError(errs, &OptionError{
+				OptionID: opt.fullID(),
+				Source:   SourceFlag,
+				Err:      errors.New("flag is set with both short and full form"),
+			})
+			continue
 		} else if shortSet {
 			stringValue = shortValue
 		}
 
-		if err := setValueByString(opt.value, stringValue); err != nil {
-			return fmt.Errorf("error parsing flag value '%v' of option '%v': %v",
-				stringValue, opt.fullID(), err)
+		err := setOptionValue(opt, SourceFlag, func(v reflect.Value) error {
+			return setValueByString(v, stringValue)
+		})
+		if err != nil {
+			errs = appendError(errs,
+				newParseError(opt, SourceFlag, stringValue, err))
+		} else if err := resolveOptionPaths(opt, "", true); err != nil {
+			errs = appendError(errs, &OptionError{
+				OptionID: opt.fullID(),
+				Source:   SourceFlag,
+				Err:      err,
+			})
 		}
 	}
 
-	if !s.conf.FlagIgnoreUnknown {
+	if !s.conf.FlagIgnoreUnknown && len(flagsMap) > 0 {
 		// error if there is still something left
+		unknown := make([]string, 0, len(flagsMap))
 		for flag := range flagsMap {
-			return fmt.Errorf("unknown flag: %v", flag)
+			unknown = append(unknown, flag)
+		}
+		errs = appendError(errs, unknownFlagsError(s, unknown))
+	}
+
+	return errs.errorOrNil()
+}
+
+// flagWord returns how the flag with the given key is written on the command
+// line, i.e. `-k` for shorthands and `--key` otherwise.
+func flagWord(key string) string {
+	if len(key) == 1 {
+		return "-" + key
+	}
+	return "--" + key
+}
+
+// knownFlags returns the full IDs of all flags that are accepted on the
+// command line.  Hidden options are left out because they should never be
+// suggested to the user.
+func knownFlags(s *setup) []string {
+	var known []string
+	for _, opt := range s.allOpts {
+		if opt.isParent || opt.hasFieldOpt(fieldOptHidden) {
+			continue
+		}
+		known = append(known, opt.fullID())
+	}
+	if !s.conf.HelpDisable {
+		known = append(known, "help", "help-all")
+	}
+	if versionEnabled(s) {
+		known = append(known, "version")
+	}
+	return known
+}
+
+// unknownFlagsError creates an error listing all the given unknown flags in
+// sorted order, each with the closest known flags as suggestions.
+func unknownFlagsError(s *setup, unknown []string) error {
+	sort.Strings(unknown)
+	known := knownFlags(s)
+
+	err := &UnknownFlagError{
+		Flags:       make([]string, len(unknown)),
+		Suggestions: make(map[string][]string),
+	}
+	for i, flag := range unknown {
+		err.Flags[i] = flagWord(flag)
+		// Suggesting alternatives for single-letter shorthands is pointless.
+		if len(flag) == 1 {
+			continue
+		}
+		if suggs := suggestions(flag, known); len(suggs) > 0 {
+			for j := range suggs {
+				suggs[j] = flagWord(suggs[j])
+			}
+			err.Suggestions[err.Flags[i]] = suggs
 		}
 	}
 
-	return nil
+	return err
 }
 
-// lookupConfigFileFlag looks for the config file in the command line flags.
-func lookupConfigFileFlag(s *setup, configOpt *option) (string, error) {
-	flagsMap, err := parseFlagsToMap(s, os.Args)
+// lookupOptionFlag looks for the value of the option in the command line
+// flags.  It is used for options that are needed before all sources are
+// parsed, like the config file.
+func lookupOptionFlag(s *setup, opt *option) (string, error) {
+	// The help message is printed when parsing all flags, so that it can
+	// show the values from the other sources, unless that does not happen
+	// because flags are disabled.
+	flagsMap, err := parseFlagsToMap(s, os.Args, s.conf.FlagDisable)
 	if err != nil {
 		return "", nil
 	}
 
-	return flagsMap[configOpt.fullID()], nil
+	return flagsMap[opt.fullID()], nil
 }
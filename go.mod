@@ -1,9 +1,18 @@
 module github.com/stevenroose/gonfig
 
+go 1.20
+
 require (
-	github.com/BurntSushi/toml v0.3.1 // indirect
+	github.com/hashicorp/hcl v1.0.0
 	github.com/pelletier/go-toml v1.2.0
 	github.com/stretchr/testify v1.3.0
 	golang.org/x/sys v0.0.0-20190116161447-11f53e031339
 	gopkg.in/yaml.v2 v2.2.2
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/BurntSushi/toml v0.3.1 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 )
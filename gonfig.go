@@ -25,16 +25,73 @@ type Conf struct {
 	// file.  If this is empty and no filename is explicitly provided, parsing
 	// a config file is skipped.
 	FileDefaultFilename string
+	// FileSearchPaths are the directories in which to look for the default
+	// config file, in order of priority.  The first file that exists is used.
+	// If this is empty or FileDefaultFilename is an absolute path, the
+	// default filename is relative to the working directory.  Use
+	// StandardSearchPaths or the SearchPath helpers to build the list.
+	FileSearchPaths []string
+	// FileRequired makes loading fail when the default config file is not
+	// found.  By default, a missing default config file is ignored.
+	FileRequired bool
+	// FileUsed, if not nil, is set to the path of the config file that was
+	// read.  It is left untouched if no config file was read.
+	FileUsed *string
 	// FileDecoder specifies the decoder function to be used for decoding the
 	// config file.  The following decoders are provided, but the user can also
 	// specify a custom decoder function:
 	//  - DecoderYAML
 	//  - DecoderTOML
 	//  - DecoderJSON
-	// If no decoder function is provided, gonfig tries to guess the function
-	// based on the file extension and otherwise tries them all in the above
-	// mentioned order.
+	//  - DecoderINI
+	//  - DecoderHCL
+	//  - DecoderJSON5 (or its alias DecoderJSONC)
+	//  - DecoderProperties
+	// The decoder is used for the config file and its profile overlay file,
+	// but not for included files and the files in FileDirectory.
+	// If no decoder function is provided, gonfig uses the decoder registered
+	// for the file extension using RegisterDecoder.  For unknown extensions,
+	// it tries to guess the format from the content of the file and uses
+	// DecoderTryAll if that fails.
+	// Note that .conf files are decoded with DecoderINI, while older versions
+	// used DecoderTryAll for them.  Set FileDecoder explicitly if your .conf
+	// file is in another format, or use RegisterDecoder to change the decoder
+	// for all .conf files.
 	FileDecoder FileDecoderFn
+	// FilePositions specifies the function used to locate the keys in the
+	// config file, so that errors can mention the file, line and column of
+	// invalid values.  The following functions are provided:
+	//  - PositionsYAML
+	//  - PositionsTOML
+	//  - PositionsJSON
+	//  - PositionsINI
+	//  - PositionsHCL
+	//  - PositionsJSON5
+	//  - PositionsProperties
+	// If no function is provided and the decoder is one of the decoders
+	// provided by gonfig, the matching function is used.
+	FilePositions FilePositionsFn
+	// FileStrict makes loading fail when the config file contains keys that
+	// do not correspond to any config variable.  By default, such keys are
+	// silently ignored.
+	FileStrict bool
+	// FileIncludeKey is the key in config files that lists other config files
+	// to include.  Its value can be a single path or a list of paths, which
+	// can contain glob patterns.  Relative paths are relative to the directory
+	// of the including file.  The values in the including file take
+	// precedence over the values in the included files.  The key is only
+	// recognised at the top level and including is disabled if it is empty.
+	FileIncludeKey string
+	// FileDirectory is a directory, like /etc/app/conf.d, of which all config
+	// files are loaded in lexical order after the config file.  The values of
+	// later files take precedence over those of earlier files and of the
+	// config file.  A relative path is relative to the directory of the config
+	// file.  If the directory does not exist, it is ignored.
+	FileDirectory string
+	// FileDirectoryPattern is the glob pattern, like "*.yaml", that the names
+	// of the files in FileDirectory must match.  By default, all files with
+	// an extension for which a decoder is registered are used.
+	FileDirectoryPattern string
 
 	// FlagDisable disabled reading config variables from the command line flags.
 	FlagDisable bool
@@ -48,6 +105,48 @@ type Conf struct {
 	// EnvPrefix is the prefix to use for the the environment variables.
 	// gonfig does not add an underscore after the prefix.
 	EnvPrefix string
+	// EnvStrict makes loading fail when there are environment variables that
+	// start with EnvPrefix but that do not correspond to any config variable.
+	// This setting has no effect when EnvPrefix is empty.
+	EnvStrict bool
+	// EnvFile is the path to a dotenv (.env) file with KEY=VALUE lines.  The
+	// variables in this file are matched to config variables like real
+	// environment variables, but real environment variables take precedence.
+	// If the file does not exist, it is ignored.
+	EnvFile string
+
+	// Interpolate enables the expansion of variables in the values of the
+	// config file and in default values.  Variables are written as ${NAME} or
+	// ${NAME:-default}, where NAME is either the full ID of a config variable
+	// like database.host or the name of an environment variable.  References
+	// to config variables use the value from the config file or the default
+	// value.  The default after :- is used when the variable is unset or
+	// empty.  Use $$ for a literal $.
+	Interpolate bool
+	// InterpolateEnv also expands variables in the values of environment
+	// variables and of the dotenv file.  It has no effect when Interpolate is
+	// not set.
+	InterpolateEnv bool
+
+	// ProfileVariable is the config variable that selects the profile, like
+	// ConfigFileVariable does for the config file.  It is read from the
+	// command line flags and the environment variables before the config file
+	// is parsed and its default value is used if it is not set there.
+	// The selected profile is applied to the config file in two ways:
+	//  - the section for the profile under ProfileKey is merged over the
+	//    other values in the config file
+	//  - the overlay file next to the config file, like app.prod.yaml for
+	//    app.yaml, is merged over the config file
+	// The profile variable should be defined in the config struct and
+	// referred to here by its ID.
+	ProfileVariable string
+	// ProfileKey is the key in the config file under which the sections for
+	// the profiles are found.  The default is "profiles".
+	ProfileKey string
+	// Profiles lists the available profiles.  If it is not empty, selecting
+	// another profile is an error.  The profiles are listed in the help
+	// message.
+	Profiles []string
 
 	// HelpDisable disables printing the help message when the --help or -h flag
 	// is provided.  If this is false, an explicit --help flag will be added.
@@ -59,6 +158,43 @@ type Conf struct {
 	// HelpDescription is the description to print for the help flag.
 	// By default, this is "show this help menu".
 	HelpDescription string
+	// HelpShowEnv adds the name of the environment variable of every option
+	// to the help message, like [$APP_DATABASE_HOST].
+	HelpShowEnv bool
+	// HelpShowFileKey adds the key of every option in the config file to the
+	// help message, like [file: database.host].
+	HelpShowFileKey bool
+	// HelpShowValues adds the current value of every option, after parsing
+	// the config file and the environment variables, to the help message.
+	// The values of options with the secret opts flag are redacted.
+	HelpShowValues bool
+	// HelpFormatter renders the help message.  The default is
+	// HelpFormatterPlain.  HelpFormatterColor, HelpFormatterMarkdown and
+	// NewTemplateHelpFormatter provide other layouts.
+	HelpFormatter HelpFormatter
+
+	// Version is the version of the program.  If it is set, a --version flag
+	// is added that prints the version and exits the program.
+	Version string
+	// VersionFunc returns the version of the program.  It can be used instead
+	// of Version if the version is computed, and takes precedence over it.
+	VersionFunc func() string
+	// VersionDescription is the description to print for the version flag.
+	// By default, this is "print the version".
+	VersionDescription string
+	// VersionNoExit makes the Load functions return ErrVersion after printing
+	// the version instead of exiting the program.
+	VersionNoExit bool
+
+	// Logger is used to log warnings, like for deprecated options and
+	// aliases.  It has the signature of log.Printf, which is the default.
+	Logger func(format string, args ...interface{})
+
+	// Constraints are rules about which options can be set together, like
+	// ExactlyOneOf("token", "token-file") or Requires("tls.cert", "tls.key").
+	// They are checked after all sources are parsed and listed in the help
+	// message.
+	Constraints []Constraint
 }
 
 // setup is the struct that keeps track of the state of the program throughout
@@ -72,6 +208,22 @@ type setup struct {
 	// Some cached variables to avoid having to generate them twice.
 	configFilePath   string
 	customConfigFile bool // Whether the config file is user-provided.
+	// The locations where the default config file was looked for.
+	configFileSearched []string
+
+	// The selected profile and the profiles found in the config file.
+	profile      string
+	fileProfiles []string
+
+	// The raw values of the options by full ID that can be referenced in
+	// interpolations.  Only used when interpolation is enabled.
+	interpolationValues map[string]string
+
+	// Whether the version has been printed for the --version flag.
+	versionPrinted bool
+
+	// The warnings that have been logged.
+	warned map[string]bool
 }
 
 // findCustomConfigFile finds out where to look for the config file.
@@ -97,7 +249,7 @@ func findCustomConfigFile(s *setup) (string, error) {
 
 	// Look if the user specified a config file.  We go in opposite priority
 	// and return as soon as we find one.
-	path, err := lookupConfigFileFlag(s, configOpt)
+	path, err := lookupOptionFlag(s, configOpt)
 	if err != nil {
 		return "", err
 	}
@@ -105,7 +257,7 @@ func findCustomConfigFile(s *setup) (string, error) {
 		return filepath.Abs(path)
 	}
 
-	path, err = lookupConfigFileEnv(s, configOpt)
+	path, err = lookupOptionEnv(s, configOpt)
 	if err != nil {
 		return "", err
 	}
@@ -119,6 +271,10 @@ func findCustomConfigFile(s *setup) (string, error) {
 // setDefaults writes the default values in the field values if a default value
 // has been provided.
 func setDefaults(s *setup) error {
+	if s.conf.Interpolate {
+		initInterpolation(s)
+	}
+
 	for _, opt := range s.allOpts {
 		if !opt.defaultSet {
 			continue
@@ -136,42 +292,115 @@ func setDefaults(s *setup) error {
 			continue
 		}
 
-		opt.defaultValue = reflect.New(opt.value.Type()).Elem()
-		if isSlice(opt.value) {
-			if err := parseSlice(opt.defaultValue, opt.defaul); err != nil {
-				return fmt.Errorf(
-					"error parsing default value for %v: %v", opt.fullID(), err)
-			}
-		} else {
-			if err := parseSimpleValue(opt.defaultValue, opt.defaul); err != nil {
-				return fmt.Errorf(
-					"error parsing default value for %v: %v", opt.fullID(), err)
-			}
+		if err := setDefault(s, opt); err != nil {
+			return fmt.Errorf("option %v: %v", opt.fullID(), err)
+		}
+	}
+
+	return nil
+}
+
+// setDefault expands, parses and sets the default value of the option.
+func setDefault(s *setup, opt *option) error {
+	defaul := opt.defaul
+	if s.conf.Interpolate {
+		var err error
+		defaul, err = interpolate(s, defaul, []string{opt.fullID()})
+		if err != nil {
+			return fmt.Errorf("error expanding default value: %v", err)
 		}
+	}
 
-		if err := setValue(opt.value, opt.defaultValue); err != nil {
-			return fmt.Errorf("error setting default value for option "+
-				"'%v' to '%v': %v", opt.id, opt.defaultValue, err)
+	opt.defaultValue = reflect.New(opt.value.Type()).Elem()
+	if isSlice(opt.value) {
+		if err := parseSlice(opt.defaultValue, defaul); err != nil {
+			return fmt.Errorf("error parsing default value: %v", err)
+		}
+	} else {
+		if err := parseSimpleValue(opt.defaultValue, defaul); err != nil {
+			return fmt.Errorf("error parsing default value: %v", err)
 		}
 	}
 
+	if err := setValue(opt.value, opt.defaultValue); err != nil {
+		return fmt.Errorf("error setting default value to '%v': %v",
+			opt.defaultValue, err)
+	}
+
+	// Default paths are not checked for existence because they are often
+	// overridden.
+	if err := resolveOptionPaths(opt, "", false); err != nil {
+		return fmt.Errorf("error in default value: %v", err)
+	}
 	return nil
 }
 
+// parseEnvAndFlags parses the dotenv file, the environment variables and the
+// command line flags unless they are disabled, and then checks the
+// constraints.  All errors are returned together.
+func parseEnvAndFlags(s *setup) error {
+	var errs LoadErrors
+
+	if !s.conf.EnvDisable {
+		if s.conf.EnvFile != "" {
+			errs = appendError(errs, parseDotEnvFile(s))
+		}
+		errs = appendError(errs, parseEnv(s))
+	}
+
+	if !s.conf.FlagDisable {
+		errs = appendError(errs, parseFlags(s))
+	}
+
+	// All sources have been parsed now.
+	warnDeprecatedOptions(s)
+	errs = appendError(errs, checkConstraints(s))
+
+	return errs.errorOrNil()
+}
+
 // Load loads the configuration of your program in the struct at c.
 // Use conf to specify how gonfig should look for configuration variables.
 //
 // This method can panic if there was a problem in the configuration struct that
 // is used (which should not happen at runtime), but will always try to produce
 // an error instead if the user provided incorrect values.
+// All errors from all sources are collected and returned together as a
+// LoadErrors value.  The individual errors are of the types *ParseError,
+// *OptionError, *FlagSyntaxError, *UnknownFlagError, *UnknownKeyError,
+// *FileNotFoundError, *DecodeError or *ConstraintError and can be inspected
+// using errors.As.
 //
 // The recognised tags on the exported struct variables are:
 //  - id: the keyword identifier (defaults to lowercase of variable name)
 //  - default: the default value of the variable
 //  - short: the shorthand used for command line flags (like -h)
-//  - desc: the description of the config var, used in --help
+//  - desc: the description of the config var, used in --help.  For nested
+//    structs, it is used as the title of their section in --help.
+//  - group: the title of the section in --help to show the config var in
+//  - alias: comma-separated old full IDs of the config var, like
+//    "listen,server.bind".  The old keys in the config file, environment
+//    variables and flags still set the config var, but a warning is logged
+//    using Conf.Logger.
+//  - deprecated: marks the config var as deprecated, with an optional message
+//    like "use server.address".  A warning is logged when it is set and it
+//    is marked as deprecated in --help.
 //  - opts: comma-separated flags.  Supported flags are:
 //     - hidden: Hides the option from help outputs.
+//     - advanced: Only shows the option in the output of --help-all.
+//     - secret: Redacts the value and the default value of the option in help
+//       outputs.
+//     - path: Treats the value as a path.  A leading ~ is expanded to the
+//       home directory and relative paths are made absolute.  Paths from the
+//       config file are relative to its directory, other paths are relative
+//       to the working directory.  This is implied for the Path type.
+//     - exists: Like path, but also checks that the path exists.
+//     - append: For slices, appends the values from every source to the
+//       values from the previous sources instead of replacing them.
+//     - replace: For maps, replaces all keys from the previous sources
+//       instead of merging the keys.  This is the default for slices.
+//     - merge: For maps, merges the keys from every source with the keys
+//       from the previous sources.  This is the default for maps.
 func Load(c interface{}, conf Conf) error {
 	s := &setup{
 		conf: &conf,
@@ -185,7 +414,13 @@ func Load(c interface{}, conf Conf) error {
 		panic(fmt.Errorf("error in default values: %v", err))
 	}
 
+	if err := checkConstraintOptions(s); err != nil {
+		panic(fmt.Errorf("error in constraints: %v", err))
+	}
+
 	// Parse in order of opposite priority: file, env, flags
+	// We don't stop at the first error so that all errors are reported at once.
+	var errs LoadErrors
 
 	if !s.conf.FileDisable {
 		filename, err := findCustomConfigFile(s)
@@ -193,40 +428,26 @@ func Load(c interface{}, conf Conf) error {
 			return err
 		}
 
+		profile, err := findProfile(s)
+		errs = appendError(errs, err)
+		s.profile = profile
+
 		if filename != "" {
 			s.customConfigFile = true
 		} else {
 			s.customConfigFile = false
-			if s.conf.FileDefaultFilename != "" {
-				filename, err = filepath.Abs(s.conf.FileDefaultFilename)
-				if err != nil {
-					return fmt.Errorf("failed to convert default config file "+
-						"location to an absolute path: %v", err)
-				}
-			}
+			filename = findDefaultConfigFile(s)
 		}
 
 		if filename != "" {
 			s.configFilePath = filename
-			if err := parseFile(s); err != nil {
-				return err
-			}
-		}
-	}
-
-	if !s.conf.EnvDisable {
-		if err := parseEnv(s); err != nil {
-			return err
+			errs = appendError(errs, parseFile(s))
 		}
 	}
 
-	if !s.conf.FlagDisable {
-		if err := parseFlags(s); err != nil {
-			return err
-		}
-	}
+	errs = appendError(errs, parseEnvAndFlags(s))
 
-	return nil
+	return errs.errorOrNil()
 }
 
 // LoadRawFile loads the configuration of your program in the struct at c from
@@ -261,27 +482,23 @@ func LoadWithRawFile(c interface{}, fileContent []byte, conf Conf) error {
 		panic(fmt.Errorf("config: error in default values: %v", err))
 	}
 
+	if err := checkConstraintOptions(s); err != nil {
+		panic(fmt.Errorf("config: error in constraints: %v", err))
+	}
+
 	if s.conf.FileDisable {
 		panic("config: can't use LoadWithRawFile with DisableFile set to true")
 	}
 
-	if err := parseFileContent(s, fileContent); err != nil {
-		return err
-	}
+	profile, err := findProfile(s)
+	errs := appendError(nil, err)
+	s.profile = profile
 
-	if !s.conf.EnvDisable {
-		if err := parseEnv(s); err != nil {
-			return err
-		}
-	}
+	errs = appendError(errs, parseFileContent(s, fileContent))
 
-	if !s.conf.FlagDisable {
-		if err := parseFlags(s); err != nil {
-			return err
-		}
-	}
+	errs = appendError(errs, parseEnvAndFlags(s))
 
-	return nil
+	return errs.errorOrNil()
 }
 
 // LoadWithMap loads the configuration of your program in the struct at c
@@ -315,21 +532,29 @@ func LoadWithMap(c interface{}, vars map[string]interface{}, conf Conf) error {
 		panic(fmt.Errorf("config: error in default values: %v", err))
 	}
 
-	if err := parseMapOpts(vars, s.allOpts); err != nil {
-		return err
+	if err := checkConstraintOptions(s); err != nil {
+		panic(fmt.Errorf("config: error in constraints: %v", err))
 	}
 
-	if !s.conf.EnvDisable {
-		if err := parseEnv(s); err != nil {
-			return err
-		}
+	// Copy the map so that the aliases can be resolved in place.
+	vars = copyMap(vars)
+	resolveMapAliases(s, vars, nil, SourceMap)
+
+	var errs LoadErrors
+	if s.conf.Interpolate {
+		addInterpolationValues(s, vars, s.opts)
+		var err error
+		vars, err = interpolateMap(s, vars, s.opts, SourceMap)
+		errs = appendError(errs, err)
 	}
 
-	if !s.conf.FlagDisable {
-		if err := parseFlags(s); err != nil {
-			return err
-		}
+	errs = appendError(errs, parseMapOpts(vars, s.opts, SourceMap))
+	errs = appendError(errs, resolveMapPaths(vars, s.opts, "", SourceMap))
+	if s.conf.Interpolate {
+		errs = appendError(errs, reapplyDefaults(s, SourceMap))
 	}
 
-	return nil
+	errs = appendError(errs, parseEnvAndFlags(s))
+
+	return errs.errorOrNil()
 }
@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -981,3 +982,113 @@ func TestLoadWithMap(t *testing.T) {
 	assert.EqualValues(t, "stringvalue", config.StringVar)
 	assert.EqualValues(t, 44, config.UintVar)
 }
+
+func TestUnknownFlags(t *testing.T) {
+	setOS([]string{"--listen-adress", "x", "--zzz", "--listen-prot", "5"}, nil)
+
+	config := struct {
+		ListenAddress string `id:"listen-address"`
+		ListenPort    int    `id:"listen-port"`
+	}{}
+	err := Load(&config, Conf{FileDisable: true, EnvDisable: true})
+	require.Error(t, err)
+	assert.Equal(t, "unknown flags: "+
+		"--listen-adress (did you mean --listen-address?), "+
+		"--listen-prot (did you mean --listen-port?), "+
+		"--zzz", err.Error())
+}
+
+func TestEnvStrict(t *testing.T) {
+	setOS(nil, map[string]string{
+		"MYAPP_DATABSE_URL":   "postgres://",
+		"MYAPP_LABELS_REGION": "eu",
+		"OTHER_VAR":           "ignored",
+	})
+
+	config := struct {
+		Database struct {
+			URL string
+		}
+		Labels map[string]interface{}
+	}{}
+	conf := Conf{FileDisable: true, FlagDisable: true, EnvPrefix: "MYAPP_"}
+	require.NoError(t, Load(&config, conf))
+
+	conf.EnvStrict = true
+	err := Load(&config, conf)
+	require.Error(t, err)
+	assert.Equal(t, "unknown environment variables with prefix MYAPP_: "+
+		"MYAPP_DATABSE_URL (did you mean MYAPP_DATABASE_URL?)", err.Error())
+
+	setOS(nil, map[string]string{
+		"MYAPP_DATABASE_URL":  "postgres://",
+		"MYAPP_LABELS_REGION": "eu",
+	})
+	require.NoError(t, Load(&config, conf))
+	assert.Equal(t, "postgres://", config.Database.URL)
+	assert.Equal(t, "eu", config.Labels["region"])
+}
+
+func TestLoadErrors(t *testing.T) {
+	setOS([]string{"--port", "abc", "--unknown"}, map[string]string{
+		"TIMEOUT": "xyz",
+	})
+
+	config := struct {
+		Port    int
+		Timeout int
+		Name    string
+	}{}
+	err := LoadWithRawFile(&config, []byte(`{"name": 5, "timeout": 3}`), Conf{
+		FileDecoder: DecoderJSON,
+	})
+	require.Error(t, err)
+
+	var loadErrs LoadErrors
+	require.True(t, errors.As(err, &loadErrs))
+	require.Len(t, loadErrs, 4)
+
+	var parseErr *ParseError
+	require.True(t, errors.As(loadErrs[0], &parseErr))
+	assert.Equal(t, "name", parseErr.OptionID)
+	assert.Equal(t, SourceFile, parseErr.Source)
+	assert.Equal(t, "5", parseErr.Value)
+	require.True(t, errors.As(loadErrs[1], &parseErr))
+	assert.Equal(t, "timeout", parseErr.OptionID)
+	assert.Equal(t, SourceEnv, parseErr.Source)
+	require.True(t, errors.As(loadErrs[2], &parseErr))
+	assert.Equal(t, "port", parseErr.OptionID)
+	assert.Equal(t, SourceFlag, parseErr.Source)
+	assert.Equal(t, "abc", parseErr.Value)
+	assert.Equal(t, reflect.TypeOf(0), parseErr.Type)
+	var unknownErr *UnknownFlagError
+	require.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, []string{"--unknown"}, unknownErr.Flags)
+
+	assert.Contains(t, err.Error(), "4 errors occurred while loading config")
+}
+
+func TestLoad_ErrorTypes(t *testing.T) {
+	config := struct {
+		Config string
+		V      int
+	}{}
+
+	setOS([]string{"--config", "/doesntexist.conf"}, nil)
+	err := Load(&config, Conf{ConfigFileVariable: "config", EnvDisable: true})
+	var notFoundErr *FileNotFoundError
+	require.True(t, errors.As(err, &notFoundErr))
+	assert.Equal(t, "/doesntexist.conf", notFoundErr.Path)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	setOS(nil, nil)
+	err = LoadRawFile(&config, []byte("{"), Conf{FileDecoder: DecoderJSON})
+	var decodeErr *DecodeError
+	require.True(t, errors.As(err, &decodeErr))
+
+	setOS([]string{"v"}, nil)
+	err = Load(&config, Conf{FileDisable: true, EnvDisable: true})
+	var syntaxErr *FlagSyntaxError
+	require.True(t, errors.As(err, &syntaxErr))
+	assert.Equal(t, "v", syntaxErr.Arg)
+}
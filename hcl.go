@@ -0,0 +1,155 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/token"
+)
+
+// DecoderHCL is the HCL decoding function for config files.
+//
+// Attributes are decoded into simple values, lists and maps.  Blocks are
+// decoded into maps, so that they can be used for nested structs.  Labeled
+// blocks like `service "web" { ... }` are decoded into maps keyed by their
+// labels, so that they can be used for maps or for nested structs with the
+// labels as field IDs.  Unlabeled blocks that are repeated are decoded into a
+// list.  All of these can be used for slices of structs: a single block gives
+// one element and labeled blocks give one element per label, in the lexical
+// order of the labels.
+var DecoderHCL FileDecoderFn = func(c []byte) (map[string]interface{}, error) {
+	m, _, err := parseHCL(c)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing HCL config file: %v", err)
+	}
+	return m, nil
+}
+
+// PositionsHCL is the FilePositionsFn for HCL config files.
+var PositionsHCL FilePositionsFn = func(c []byte) (map[string]Position, error) {
+	_, positions, err := parseHCL(c)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing HCL config file: %v", err)
+	}
+	return positions, nil
+}
+
+// parseHCL parses the HCL content into a map and the positions of its keys.
+func parseHCL(c []byte) (map[string]interface{}, map[string]Position, error) {
+	file, err := hcl.ParseBytes(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	list, ok := file.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, nil, fmt.Errorf("unexpected root node of type %T", file.Node)
+	}
+
+	positions := make(map[string]Position)
+	m, err := hclObject(list, "", positions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, positions, nil
+}
+
+// hclPosition converts an HCL position.
+func hclPosition(pos token.Pos) Position {
+	return Position{Line: pos.Line, Column: pos.Column}
+}
+
+// hclObject converts a list of HCL object items into a map.  The positions of
+// the keys, prefixed with prefix, are added to positions if it is not nil.
+func hclObject(list *ast.ObjectList, prefix string,
+	positions map[string]Position) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for _, item := range list.Items {
+		// Labeled blocks have more than one key.  All but the last key are
+		// used for nesting.
+		target, path := result, prefix
+		for i, key := range item.Keys {
+			name, ok := key.Token.Value().(string)
+			if !ok {
+				return nil, fmt.Errorf("%v: invalid key %v",
+					key.Pos(), key.Token.Text)
+			}
+			if _, found := positions[path+name]; positions != nil && !found {
+				positions[path+name] = hclPosition(key.Pos())
+			}
+
+			if i == len(item.Keys)-1 {
+				break
+			}
+
+			sub, ok := target[name].(map[string]interface{})
+			if !ok {
+				if _, exists := target[name]; exists {
+					return nil, fmt.Errorf("%v: block %v conflicts with an "+
+						"attribute with the same name", key.Pos(), path+name)
+				}
+				sub = make(map[string]interface{})
+				target[name] = sub
+			}
+			target, path = sub, path+name+"."
+		}
+
+		name := item.Keys[len(item.Keys)-1].Token.Value().(string)
+		value, err := hclValue(item.Val, path+name+".", positions)
+		if err != nil {
+			return nil, err
+		}
+
+		existing, exists := target[name]
+		isBlock := !item.Assign.IsValid()
+		switch {
+		case !exists:
+			target[name] = value
+		case !isBlock:
+			return nil, fmt.Errorf("%v: duplicate attribute %v",
+				item.Pos(), path+name)
+		default:
+			// Repeated blocks form a list.
+			if blocks, ok := existing.([]interface{}); ok {
+				target[name] = append(blocks, value)
+			} else {
+				target[name] = []interface{}{existing, value}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// hclValue converts an HCL value node into a Go value.
+func hclValue(node ast.Node, prefix string,
+	positions map[string]Position) (interface{}, error) {
+	switch n := node.(type) {
+	case *ast.LiteralType:
+		return n.Token.Value(), nil
+
+	case *ast.ListType:
+		list := make([]interface{}, len(n.List))
+		for i, elem := range n.List {
+			// We don't keep track of positions inside lists.
+			v, err := hclValue(elem, "", nil)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+
+	case *ast.ObjectType:
+		return hclObject(n.List, prefix, positions)
+
+	default:
+		return nil, fmt.Errorf("%v: unsupported value of type %T",
+			node.Pos(), node)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoderHCL(t *testing.T) {
+	m, err := DecoderHCL([]byte(`
+# comment
+name = "app"
+ports = [80, 443]
+
+database {
+  host = "db.local"
+  pool = { size = 10 }
+}
+
+service "web" {
+  port = 8080
+}
+
+service "api" {
+  port = 9090
+}
+
+backend {
+  addr = "a"
+}
+
+backend {
+  addr = "b"
+}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":  "app",
+		"ports": []interface{}{int64(80), int64(443)},
+		"database": map[string]interface{}{
+			"host": "db.local",
+			"pool": map[string]interface{}{"size": int64(10)},
+		},
+		"service": map[string]interface{}{
+			"web": map[string]interface{}{"port": int64(8080)},
+			"api": map[string]interface{}{"port": int64(9090)},
+		},
+		"backend": []interface{}{
+			map[string]interface{}{"addr": "a"},
+			map[string]interface{}{"addr": "b"},
+		},
+	}, m)
+
+	_, err = DecoderHCL([]byte("name = \"a\"\nname = \"b\"\n"))
+	assert.Error(t, err)
+	_, err = DecoderHCL([]byte("name = {\n"))
+	assert.Error(t, err)
+}
+
+func TestLoad_HCL(t *testing.T) {
+	type service struct {
+		Port int
+	}
+	config := struct {
+		Name     string
+		Ports    []int
+		Database struct {
+			Host string
+		}
+		Service struct {
+			Web service
+			API service `id:"api"`
+		}
+		Backend []struct {
+			Addr string
+		}
+		Labels map[string]interface{}
+	}{}
+
+	s := &setup{configFilePath: "/etc/app.hcl", conf: &Conf{}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+	require.NoError(t, parseFileContent(s, []byte(`
+name = "app"
+ports = [80, 443]
+database {
+  host = "db.local"
+}
+service "web" { port = 8080 }
+service "api" { port = 9090 }
+backend { addr = "a" }
+backend { addr = "b" }
+labels "zone" {}
+`)))
+	assert.Equal(t, "app", config.Name)
+	assert.Equal(t, []int{80, 443}, config.Ports)
+	assert.Equal(t, "db.local", config.Database.Host)
+	assert.Equal(t, 8080, config.Service.Web.Port)
+	assert.Equal(t, 9090, config.Service.API.Port)
+	if assert.Len(t, config.Backend, 2) {
+		assert.Equal(t, "a", config.Backend[0].Addr)
+		assert.Equal(t, "b", config.Backend[1].Addr)
+	}
+	assert.Contains(t, config.Labels, "zone")
+
+	// Labeled blocks and single blocks can be used for slices of structs.
+	s = &setup{configFilePath: "/etc/app.hcl", conf: &Conf{}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+	require.NoError(t, parseFileContent(s, []byte(`
+backend "b" { addr = "b" }
+backend "a" { addr = "a" }
+`)))
+	if assert.Len(t, config.Backend, 2) {
+		assert.Equal(t, "a", config.Backend[0].Addr)
+		assert.Equal(t, "b", config.Backend[1].Addr)
+	}
+
+	s = &setup{configFilePath: "/etc/app.hcl", conf: &Conf{}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+	require.NoError(t, parseFileContent(s, []byte(`backend { addr = "c" }`)))
+	if assert.Len(t, config.Backend, 1) {
+		assert.Equal(t, "c", config.Backend[0].Addr)
+	}
+
+	positions, err := PositionsHCL([]byte("a = 1\nsvc \"web\" {\n  port = 1\n}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, Position{Line: 3, Column: 3}, positions["svc.web.port"])
+}
@@ -1,6 +1,7 @@
 package gonfig
 
 import (
+	"encoding"
 	"fmt"
 	"io"
 	"os"
@@ -10,8 +11,9 @@ import (
 )
 
 const (
-	defaultHelpDescription = "print this help menu"
-	defaultHelpMessage     = "Usage of __EXEC__:"
+	defaultHelpDescription    = "print this help menu"
+	defaultHelpAllDescription = "print this help menu with all options"
+	defaultHelpMessage        = "Usage of __EXEC__:"
 )
 
 func typeString(t reflect.Type) string {
@@ -134,93 +136,258 @@ func wrap(i, w int, s string) string {
 
 }
 
-// writeHelpMessage writes the help message to the writer.
-//
-// This implementation is borrowed from https://github.com/spf13/pflag
-func writeHelpMessage(s *setup, w io.Writer) {
-	lines := make([]string, 0, len(s.allOpts))
+// helpSection is a titled section of the help message.
+type helpSection struct {
+	title string
+	opts  []*option
+}
 
-	maxlen := 0
-	for _, opt := range s.allOpts {
-		if opt.isParent {
-			continue
-		}
-		if opt.hasFieldOpt(fieldOptHidden) {
-			continue
+// helpSections divides the options that should be shown in the help message
+// into sections, in the order in which they are declared in the config
+// struct.  Top-level options without a group are put in the first section,
+// which has no title.  The options of nested structs are put in a section
+// titled with the description of the struct, or with its full ID if it has
+// no description, unless they have a group.  Options that have the advanced
+// opts flag, or that are nested in a struct that has it, are only included
+// if all is set.  The second return value indicates whether options were left
+// out because of this.
+func helpSections(s *setup, all bool) ([]*helpSection, bool) {
+	main := &helpSection{}
+	sections := []*helpSection{main}
+	byTitle := map[string]*helpSection{"": main}
+	section := func(title string) *helpSection {
+		if sec, ok := byTitle[title]; ok {
+			return sec
 		}
+		sec := &helpSection{title: title}
+		byTitle[title] = sec
+		sections = append(sections, sec)
+		return sec
+	}
 
-		line := ""
-		if opt.short != "" {
-			line = fmt.Sprintf("  -%v, --%v", opt.short, opt.fullID())
-		} else {
-			line = fmt.Sprintf("      --%v", opt.fullID())
-		}
+	hiddenAdvanced := false
+	var walk func(opts []*option, current *helpSection)
+	walk = func(opts []*option, current *helpSection) {
+		for _, opt := range opts {
+			if opt.hasFieldOpt(fieldOptHidden) {
+				continue
+			}
+			if opt.hasFieldOpt(fieldOptAdvanced) && !all {
+				hiddenAdvanced = true
+				continue
+			}
 
-		typeStr := typeString(opt.value.Type())
-		varname, desc := unquoteDescription(opt.desc)
-		if opt.isMap {
-			line += ".<key> <value>"
-		} else {
-			if varname == "" {
-				varname = typeStr
-				if varname == "bool" {
-					// We don't want to show a varname for bools.
-					varname = ""
-				}
+			target := current
+			if opt.group != "" {
+				target = section(opt.group)
 			}
 
-			if varname != "" {
-				line += " " + varname
+			if opt.isParent {
+				if opt.group == "" {
+					title := opt.desc
+					if title == "" {
+						title = opt.fullID()
+					}
+					target = section(title)
+				}
+				walk(opt.subOpts, target)
+				continue
 			}
+
+			target.opts = append(target.opts, opt)
 		}
+	}
+	walk(s.opts, main)
+
+	return sections, hiddenAdvanced
+}
 
-		// This special character will be replaced with spacing once the
-		// correct alignment is calculated
-		line += "\x00"
-		if len(line) > maxlen {
-			maxlen = len(line)
+// formatValue formats the value of an option for the help message.
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
 		}
+		v = v.Elem()
+	}
 
-		line += desc
-		if opt.defaul != "" {
-			if len(typeStr) >= 6 && typeStr[0:6] == "string" {
-				// Put quotes around string types.
-				line += fmt.Sprintf(" (default %q)", opt.defaul)
-			} else {
-				line += fmt.Sprintf(" (default %v)", opt.defaul)
-			}
+	if !v.Type().Implements(typeOfTextMarshaler) && v.CanAddr() &&
+		v.Addr().Type().Implements(typeOfTextMarshaler) {
+		v = v.Addr()
+	}
+	if v.Type().Implements(typeOfTextMarshaler) {
+		if text, err := v.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+
+	switch {
+	case v.Kind() == reflect.String:
+		return fmt.Sprintf("%q", v.String())
+	case isSlice(v):
+		elems := make([]string, v.Len())
+		for i := range elems {
+			elems[i] = formatValue(v.Index(i))
 		}
+		return strings.Join(elems, ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// newHelpOption creates the model of the option for the help message.
+func newHelpOption(s *setup, opt *option, group string) *HelpOption {
+	typeStr := typeString(opt.value.Type())
+	varname, desc := unquoteDescription(opt.desc)
+	if varname == "" && !opt.isMap {
+		varname = typeStr
+		if varname == "bool" {
+			// We don't want to show a varname for bools.
+			varname = ""
+		}
+	}
+
+	h := &HelpOption{
+		ID:          opt.id,
+		FullID:      opt.fullID(),
+		Short:       opt.short,
+		Type:        typeStr,
+		VarName:     varname,
+		Default:     opt.defaul,
+		Description: desc,
+		Group:       group,
+		IsMap:       opt.isMap,
+		Hidden:      opt.hasFieldOpt(fieldOptHidden),
+		Advanced:    opt.hasFieldOpt(fieldOptAdvanced),
+		Secret:      opt.hasFieldOpt(fieldOptSecret),
+
+		Deprecated:        opt.isDeprecated,
+		DeprecatedMessage: opt.deprecated,
+	}
+	for _, alias := range opt.aliases {
+		h.Aliases = append(h.Aliases, strings.Join(alias, "."))
+	}
 
-		lines = append(lines, line)
+	// The value of a secret often is its default, so that is redacted too.
+	if h.Secret && h.Default != "" {
+		h.Default = "<redacted>"
 	}
 
-	helpFlagDesc := s.conf.HelpDescription
-	if helpFlagDesc == "" {
-		helpFlagDesc = defaultHelpDescription
+	if !isZero(opt.value) && !(opt.isMap && opt.value.Len() == 0) {
+		if h.Secret {
+			h.Value = "<redacted>"
+		} else {
+			h.Value = formatValue(opt.value)
+		}
+	}
+	if !s.conf.EnvDisable {
+		h.EnvName = makeEnvKey(s.conf.EnvPrefix, opt.fullIDParts)
+		if opt.isMap {
+			h.EnvName += "_<KEY>"
+		}
+	}
+	if !s.conf.FileDisable {
+		h.FileKey = opt.fullID()
 	}
-	lines = append(lines, "  -h, --help\x00"+helpFlagDesc)
 
-	message := s.conf.HelpMessage
-	if message == "" {
+	return h
+}
+
+// buildHelp creates the model of the help message.  If all is set, advanced
+// options are included in the sections as well.
+func buildHelp(s *setup, all bool) *Help {
+	help := &Help{
+		ShowEnv:     s.conf.HelpShowEnv,
+		ShowFileKey: s.conf.HelpShowFileKey,
+		ShowValues:  s.conf.HelpShowValues,
+	}
+
+	help.Usage = s.conf.HelpMessage
+	if help.Usage == "" {
 		exec := path.Base(os.Args[0])
-		message = strings.Replace(defaultHelpMessage, "__EXEC__", exec, 1)
+		help.Usage = strings.Replace(defaultHelpMessage, "__EXEC__", exec, 1)
 	}
-	fmt.Fprintln(w, message)
 
-	terminalWidth := getTerminalWidth()
+	// All options in declaration order, including hidden ones.
+	var walk func(opts []*option)
+	walk = func(opts []*option) {
+		for _, opt := range opts {
+			if opt.isParent {
+				walk(opt.subOpts)
+			} else {
+				help.Options = append(help.Options, newHelpOption(s, opt, ""))
+			}
+		}
+	}
+	walk(s.opts)
+
+	sections, hiddenAdvanced := helpSections(s, all)
+	help.HasAdvanced = hiddenAdvanced
+	for _, section := range sections {
+		hs := &HelpSection{Title: section.title}
+		for _, opt := range section.opts {
+			hs.Options = append(hs.Options, newHelpOption(s, opt, section.title))
+		}
+		help.Sections = append(help.Sections, hs)
+	}
+
+	// Add the built-in flags to the first section.
+	if !s.conf.HelpDisable {
+		helpFlagDesc := s.conf.HelpDescription
+		if helpFlagDesc == "" {
+			helpFlagDesc = defaultHelpDescription
+		}
+		help.Sections[0].Options = append(help.Sections[0].Options, &HelpOption{
+			ID:          "help",
+			FullID:      "help",
+			Short:       "h",
+			Type:        "bool",
+			Description: helpFlagDesc,
+			Builtin:     true,
+		})
+		if hiddenAdvanced || all {
+			help.Sections[0].Options = append(help.Sections[0].Options, &HelpOption{
+				ID:          "help-all",
+				FullID:      "help-all",
+				Type:        "bool",
+				Description: defaultHelpAllDescription,
+				Builtin:     true,
+			})
+		}
+	}
+	if versionEnabled(s) {
+		help.Sections[0].Options = append(help.Sections[0].Options, versionHelpOption(s))
+	}
 
-	for _, line := range lines {
-		sidx := strings.Index(line, "\x00")
-		spacing := strings.Repeat(" ", maxlen-sidx)
-		// maxlen + 2 comes from + 1 for the \x00 and + 1 for the (deliberate)
-		// off-by-one in maxlen-sidx
-		fmt.Fprintln(w, line[:sidx], spacing,
-			wrap(maxlen+2, terminalWidth, line[sidx+1:]))
+	for _, c := range s.conf.Constraints {
+		help.Constraints = append(help.Constraints, c.String())
+	}
+	if s.conf.ProfileVariable != "" {
+		help.Profiles = availableProfiles(s)
+	}
+	if !s.conf.FileDisable && len(s.conf.FileSearchPaths) > 0 {
+		help.SearchPaths = configFileCandidates(s)
+	}
+
+	return help
+}
+
+// writeHelpMessage writes the help message to the writer using the help
+// formatter from the conf.  If all is set, advanced options are included as
+// well.
+func writeHelpMessage(s *setup, w io.Writer, all bool) {
+	formatter := s.conf.HelpFormatter
+	if formatter == nil {
+		formatter = HelpFormatterPlain
+	}
+	if err := formatter.FormatHelp(w, buildHelp(s, all)); err != nil {
+		fmt.Fprintf(w, "error writing help message: %v\n", err)
 	}
 }
 
-// printHelpAndExit prints the help message and exits the program.
-func printHelpAndExit(s *setup) {
-	writeHelpMessage(s, os.Stdout)
+// printHelpAndExit prints the help message and exits the program.  If all is
+// set, advanced options are included as well.
+func printHelpAndExit(s *setup, all bool) {
+	writeHelpMessage(s, os.Stdout, all)
 	os.Exit(2)
 }
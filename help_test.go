@@ -0,0 +1,126 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type helpConfig struct {
+	Verbose  bool   `short:"v" desc:"print more"`
+	Name     string `default:"app" desc:"the name"`
+	Database struct {
+		Host string `desc:"the host"`
+		Pool struct {
+			Size int `default:"10"`
+		}
+	} `desc:"Database options"`
+	Listen string `group:"Network" desc:"the address to listen on"`
+	Secret string `opts:"hidden"`
+	Tuning struct {
+		Workers int
+	} `opts:"advanced"`
+	Timeout int `group:"Network" opts:"advanced"`
+}
+
+func TestWriteHelpMessage(t *testing.T) {
+	config := helpConfig{}
+	s := &setup{conf: &Conf{HelpMessage: "Usage:"}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+
+	var buf bytes.Buffer
+	writeHelpMessage(s, &buf, false)
+	assert.Equal(t, `Usage:
+  -v, --verbose                  print more
+      --name string              the name (default "app")
+  -h, --help                     print this help menu
+      --help-all                 print this help menu with all options
+
+Database options:
+      --database.host string     the host
+
+database.pool:
+      --database.pool.size int    (default 10)
+
+Network:
+      --listen string            the address to listen on
+`, buf.String())
+
+	buf.Reset()
+	writeHelpMessage(s, &buf, true)
+	assert.Contains(t, buf.String(), "Network:\n"+
+		"      --listen string            the address to listen on\n"+
+		"      --timeout int              \n\n"+
+		"tuning:\n"+
+		"      --tuning.workers int       \n")
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestHelpSections_Order(t *testing.T) {
+	config := helpConfig{}
+	s := &setup{conf: &Conf{}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+
+	sections, hidden := helpSections(s, true)
+	assert.False(t, hidden)
+	var titles []string
+	for _, section := range sections {
+		titles = append(titles, section.title)
+	}
+	assert.Equal(t, []string{"", "Database options", "database.pool", "Network",
+		"tuning"}, titles)
+}
+
+type textLevel int
+
+func (l textLevel) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("level-%d", l)), nil
+}
+
+func (l *textLevel) UnmarshalText(t []byte) error {
+	_, err := fmt.Sscanf(string(t), "level-%d", (*int)(l))
+	return err
+}
+
+func TestWriteHelpMessage_Reference(t *testing.T) {
+	config := struct {
+		Name     string
+		Password string `opts:"secret"`
+		Ports    []int
+		Labels   map[string]interface{}
+		Database struct {
+			Host string
+		}
+		Level *textLevel
+	}{
+		Name:     "app",
+		Password: "hunter2",
+		Ports:    []int{80, 443},
+	}
+	s := &setup{conf: &Conf{
+		EnvPrefix:       "APP_",
+		HelpShowEnv:     true,
+		HelpShowFileKey: true,
+		HelpShowValues:  true,
+	}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+	*config.Level = 2
+
+	var buf bytes.Buffer
+	writeHelpMessage(s, &buf, false)
+	out := buf.String()
+	assert.Contains(t, out, "(current \"app\") [$APP_NAME] [file: name]\n")
+	assert.Contains(t, out, `(current <redacted>) [$APP_PASSWORD]`)
+	assert.NotContains(t, out, "hunter2")
+	assert.Contains(t, out, `(current 80,443) [$APP_PORTS] [file: ports]`)
+	assert.Contains(t, out, ` [$APP_LABELS_<KEY>] [file: labels]`)
+	assert.Contains(t, out, ` [$APP_DATABASE_HOST] [file: database.host]`)
+	assert.Contains(t, out, `(current level-2)`)
+}
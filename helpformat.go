@@ -0,0 +1,376 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// HelpOption describes an option in the help message.
+type HelpOption struct {
+	// ID is the ID of the option, without the IDs of its parents.
+	ID string
+	// FullID is the ID of the option including the IDs of its parents,
+	// separated by dots.  This is also the name of the flag.
+	FullID string
+	// Short is the short flag of the option, if any.
+	Short string
+	// Type is the name of the type of the option, like "string" or "int...".
+	Type string
+	// VarName is the name to show for the value of the flag.  It is the
+	// back-quoted name in the description or the type name, and it is empty
+	// for bools and maps.
+	VarName string
+	// Default is the default value from the default tag, or "<redacted>"
+	// for options with the secret opts flag.
+	Default string
+	// Description is the description of the option, without back quotes.
+	Description string
+	// Group is the title of the section the option is shown in.
+	Group string
+	// EnvName is the name of the environment variable for the option.  It is
+	// empty if environment variables are disabled.
+	EnvName string
+	// FileKey is the key of the option in the config file.  It is empty if
+	// config files are disabled.
+	FileKey string
+	// Value is the current value of the option, or "<redacted>" for options
+	// with the secret opts flag.  It is empty if the option has no value.
+	Value string
+	// Aliases are the old full IDs of the option from the alias tag.
+	Aliases []string
+	// DeprecatedMessage is the message from the deprecated tag.
+	DeprecatedMessage string
+
+	// IsMap indicates that the option is a map that takes keys.
+	IsMap bool
+	// Hidden indicates that the option has the hidden opts flag.
+	Hidden bool
+	// Advanced indicates that the option has the advanced opts flag.
+	Advanced bool
+	// Secret indicates that the option has the secret opts flag.
+	Secret bool
+	// Deprecated indicates that the option has the deprecated tag.
+	Deprecated bool
+	// Builtin indicates that the option is a flag added by gonfig, like
+	// --help.
+	Builtin bool
+}
+
+// Flag returns the flags of the option as they are shown in the help
+// message, like "-p, --port int".
+func (o *HelpOption) Flag() string {
+	flag := "--" + o.FullID
+	if o.Short != "" {
+		flag = "-" + o.Short + ", " + flag
+	}
+	if o.IsMap {
+		flag += ".<key> <value>"
+	} else if o.VarName != "" {
+		flag += " " + o.VarName
+	}
+	return flag
+}
+
+// HelpSection is a titled section of the help message.
+type HelpSection struct {
+	// Title is the title of the section.  The first section has no title.
+	Title   string
+	Options []*HelpOption
+}
+
+// Help is the model of the help message that is passed to a HelpFormatter.
+type Help struct {
+	// Usage is the message printed before the options.
+	Usage string
+	// Sections holds the options to show, divided into sections.  The first
+	// section has no title and also holds the built-in flags.  Hidden options
+	// are never included and advanced options only when all options are
+	// requested.
+	Sections []*HelpSection
+	// Options holds all options in the order of declaration, including
+	// hidden and advanced ones.
+	Options []*HelpOption
+	// Constraints describes the constraints between the options, like
+	// "--tls.cert requires --tls.key".
+	Constraints []string
+	// Profiles lists the available profiles.
+	Profiles []string
+	// SearchPaths lists the locations where the config file is searched for.
+	SearchPaths []string
+
+	// ShowEnv, ShowFileKey and ShowValues correspond to the HelpShowEnv,
+	// HelpShowFileKey and HelpShowValues fields of Conf.
+	ShowEnv     bool
+	ShowFileKey bool
+	ShowValues  bool
+	// HasAdvanced indicates that advanced options were left out of the
+	// sections.
+	HasAdvanced bool
+}
+
+// HelpFormatter renders the help message.
+type HelpFormatter interface {
+	FormatHelp(w io.Writer, help *Help) error
+}
+
+// HelpFormatterFn is a function that implements HelpFormatter.
+type HelpFormatterFn func(w io.Writer, help *Help) error
+
+// FormatHelp calls the function.
+func (fn HelpFormatterFn) FormatHelp(w io.Writer, help *Help) error {
+	return fn(w, help)
+}
+
+// NewHelp creates the model of the help message for the config struct at c,
+// as it would be shown by Load with the given conf.  If all is set, advanced
+// options are included in the sections as well.  The values of the options
+// are the default values.
+func NewHelp(c interface{}, conf Conf, all bool) (*Help, error) {
+	s := &setup{
+		conf: &conf,
+	}
+	if err := inspectConfigStructure(s, c); err != nil {
+		return nil, fmt.Errorf("error in config structure: %v", err)
+	}
+	if err := setDefaults(s); err != nil {
+		return nil, fmt.Errorf("error in default values: %v", err)
+	}
+	if err := checkConstraintOptions(s); err != nil {
+		return nil, fmt.Errorf("error in constraints: %v", err)
+	}
+	return buildHelp(s, all), nil
+}
+
+// NewTemplateHelpFormatter creates a HelpFormatter that executes the template
+// with the *Help as data.
+func NewTemplateHelpFormatter(tmpl *template.Template) HelpFormatter {
+	return HelpFormatterFn(func(w io.Writer, help *Help) error {
+		return tmpl.Execute(w, help)
+	})
+}
+
+// HelpFormatterPlain renders the help message as plain text with aligned
+// descriptions.  This is the default.
+var HelpFormatterPlain HelpFormatter = HelpFormatterFn(func(w io.Writer, help *Help) error {
+	return formatHelpText(w, help, false)
+})
+
+// HelpFormatterColor renders the help message like HelpFormatterPlain, with
+// ANSI colors for terminals.
+var HelpFormatterColor HelpFormatter = HelpFormatterFn(func(w io.Writer, help *Help) error {
+	return formatHelpText(w, help, true)
+})
+
+// HelpFormatterMarkdown renders the help message as Markdown, with a table of
+// the options for every section.
+var HelpFormatterMarkdown HelpFormatter = HelpFormatterFn(formatHelpMarkdown)
+
+const (
+	ansiBold  = "\x1b[1m"
+	ansiCyan  = "\x1b[36m"
+	ansiReset = "\x1b[0m"
+)
+
+// helpDescription creates the description of the option in the help message,
+// with the default and current value and the environment variable and file
+// key if they are enabled.
+func helpDescription(help *Help, opt *HelpOption) string {
+	desc := opt.Description
+	if opt.Default != "" {
+		if strings.HasPrefix(opt.Type, "string") && !opt.Secret {
+			// Put quotes around string types.
+			desc += fmt.Sprintf(" (default %q)", opt.Default)
+		} else {
+			desc += fmt.Sprintf(" (default %v)", opt.Default)
+		}
+	}
+	if help.ShowValues && opt.Value != "" {
+		desc += fmt.Sprintf(" (current %v)", opt.Value)
+	}
+	if opt.Deprecated {
+		if opt.DeprecatedMessage != "" {
+			desc += fmt.Sprintf(" (deprecated: %v)", opt.DeprecatedMessage)
+		} else {
+			desc += " (deprecated)"
+		}
+	}
+	if help.ShowEnv && opt.EnvName != "" {
+		desc += fmt.Sprintf(" [$%v]", opt.EnvName)
+	}
+	if help.ShowFileKey && opt.FileKey != "" {
+		desc += fmt.Sprintf(" [file: %v]", opt.FileKey)
+	}
+	return desc
+}
+
+// formatHelpText renders the help message as text, optionally with colors.
+//
+// This implementation is borrowed from https://github.com/spf13/pflag
+func formatHelpText(w io.Writer, help *Help, color bool) error {
+	paint := func(code, s string) string {
+		if !color {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	flag := func(opt *HelpOption) string {
+		if opt.Short != "" {
+			return "  " + opt.Flag()
+		}
+		return "      " + opt.Flag()
+	}
+
+	// Calculate the alignment over all sections.
+	maxlen := 0
+	for _, section := range help.Sections {
+		for _, opt := range section.Options {
+			if l := len(flag(opt)) + 1; l > maxlen {
+				maxlen = l
+			}
+		}
+	}
+
+	fmt.Fprintln(w, help.Usage)
+
+	terminalWidth := getTerminalWidth()
+
+	for _, section := range help.Sections {
+		if len(section.Options) == 0 {
+			continue
+		}
+		if section.Title != "" {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, paint(ansiBold, section.Title+":"))
+		}
+		for _, opt := range section.Options {
+			line := flag(opt)
+			spacing := strings.Repeat(" ", maxlen-len(line))
+			// maxlen + 2 comes from + 1 for the separator and + 1 for the
+			// (deliberate) off-by-one in maxlen-len(line)
+			fmt.Fprintln(w, paint(ansiCyan, line), spacing,
+				wrap(maxlen+2, terminalWidth, helpDescription(help, opt)))
+		}
+	}
+
+	if len(help.Constraints) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, paint(ansiBold, "Constraints:"))
+		for _, constraint := range help.Constraints {
+			fmt.Fprintln(w, "  "+constraint)
+		}
+	}
+
+	if len(help.Profiles) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Available profiles: "+strings.Join(help.Profiles, ", "))
+	}
+
+	// List the locations of the config file if there is a choice.
+	if len(help.SearchPaths) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "The config file is searched for in:")
+		for _, candidate := range help.SearchPaths {
+			fmt.Fprintln(w, "  "+candidate)
+		}
+	}
+
+	return nil
+}
+
+// markdownEscape escapes the characters that have a special meaning in
+// Markdown table cells.
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "<", `\<`, ">", `\>`,
+		"*", `\*`, "_", `\_`).Replace(s)
+}
+
+// markdownCode formats s as inline code in a Markdown table cell.
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + strings.Replace(s, "|", `\|`, -1) + "`"
+}
+
+// writeMarkdownSections writes a table of the options for every section of
+// the help message.  Sections with a title get a heading.
+func writeMarkdownSections(w io.Writer, help *Help, showEnv, showFileKey bool) {
+	header := []string{"Flag", "Default", "Description"}
+	if showEnv {
+		header = append(header, "Environment")
+	}
+	if showFileKey {
+		header = append(header, "File key")
+	}
+
+	for _, section := range help.Sections {
+		if len(section.Options) == 0 {
+			continue
+		}
+		fmt.Fprintln(w)
+		if section.Title != "" {
+			fmt.Fprintf(w, "## %v\n\n", markdownEscape(section.Title))
+		}
+		fmt.Fprintf(w, "| %v |\n", strings.Join(header, " | "))
+		fmt.Fprintf(w, "|%v\n", strings.Repeat(" --- |", len(header)))
+		for _, opt := range section.Options {
+			desc := markdownEscape(opt.Description)
+			if help.ShowValues && opt.Value != "" {
+				desc += " (current " + markdownCode(opt.Value) + ")"
+			}
+			if opt.Deprecated {
+				desc += " (deprecated"
+				if opt.DeprecatedMessage != "" {
+					desc += ": " + markdownEscape(opt.DeprecatedMessage)
+				}
+				desc += ")"
+			}
+			cells := []string{markdownCode(opt.Flag()), markdownCode(opt.Default), desc}
+			if showEnv {
+				cells = append(cells, markdownCode(opt.EnvName))
+			}
+			if showFileKey {
+				cells = append(cells, markdownCode(opt.FileKey))
+			}
+			fmt.Fprintf(w, "| %v |\n", strings.Join(cells, " | "))
+		}
+	}
+}
+
+// formatHelpMarkdown renders the help message as Markdown.
+func formatHelpMarkdown(w io.Writer, help *Help) error {
+	fmt.Fprintln(w, markdownEscape(help.Usage))
+	writeMarkdownSections(w, help, help.ShowEnv, help.ShowFileKey)
+
+	if len(help.Constraints) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Constraints:")
+		fmt.Fprintln(w)
+		for _, constraint := range help.Constraints {
+			fmt.Fprintf(w, "- %v\n", markdownEscape(constraint))
+		}
+	}
+
+	if len(help.Profiles) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Available profiles: "+markdownEscape(strings.Join(help.Profiles, ", ")))
+	}
+
+	if len(help.SearchPaths) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "The config file is searched for in:")
+		fmt.Fprintln(w)
+		for _, candidate := range help.SearchPaths {
+			fmt.Fprintf(w, "- %v\n", markdownCode(candidate))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHelp(t *testing.T) {
+	config := helpConfig{}
+	help, err := NewHelp(&config, Conf{EnvPrefix: "APP_", HelpMessage: "Usage:"}, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Usage:", help.Usage)
+	assert.True(t, help.HasAdvanced)
+	require.Len(t, help.Sections, 4)
+
+	main := help.Sections[0]
+	require.Len(t, main.Options, 4)
+	assert.Equal(t, &HelpOption{
+		ID:          "verbose",
+		FullID:      "verbose",
+		Short:       "v",
+		Type:        "bool",
+		Description: "print more",
+		EnvName:     "APP_VERBOSE",
+		FileKey:     "verbose",
+	}, main.Options[0])
+	assert.Equal(t, "--name string", main.Options[1].Flag())
+	assert.Equal(t, `"app"`, main.Options[1].Value)
+	assert.True(t, main.Options[2].Builtin)
+	assert.Equal(t, "-h, --help", main.Options[2].Flag())
+	assert.Equal(t, "--help-all", main.Options[3].Flag())
+
+	assert.Equal(t, "Database options", help.Sections[1].Options[0].Group)
+
+	// All options are listed, including hidden and advanced ones.
+	var ids []string
+	for _, opt := range help.Options {
+		ids = append(ids, opt.FullID)
+	}
+	assert.Equal(t, []string{"verbose", "name", "database.host", "database.pool.size",
+		"listen", "secret", "tuning.workers", "timeout"}, ids)
+	assert.True(t, help.Options[5].Hidden)
+	assert.True(t, help.Options[7].Advanced)
+}
+
+func TestHelpFormatter_Template(t *testing.T) {
+	tmpl := template.Must(template.New("help").Parse(
+		"{{.Usage}}\n{{range .Options}}{{if not .Hidden}}{{.Flag}}: {{.Description}}\n{{end}}{{end}}"))
+	config := struct {
+		Name   string `desc:"the name"`
+		Secret string `opts:"hidden"`
+	}{}
+	s := &setup{conf: &Conf{
+		HelpMessage:   "Usage:",
+		HelpFormatter: NewTemplateHelpFormatter(tmpl),
+	}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+
+	var buf bytes.Buffer
+	writeHelpMessage(s, &buf, false)
+	assert.Equal(t, "Usage:\n--name string: the name\n", buf.String())
+}
+
+func TestHelpFormatter_Color(t *testing.T) {
+	config := helpConfig{}
+	s := &setup{conf: &Conf{HelpMessage: "Usage:", HelpFormatter: HelpFormatterColor}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+
+	var buf bytes.Buffer
+	writeHelpMessage(s, &buf, false)
+	out := buf.String()
+	assert.Contains(t, out, "\x1b[36m  -v, --verbose\x1b[0m                  print more\n")
+	assert.Contains(t, out, "\x1b[1mNetwork:\x1b[0m\n")
+
+	// Without the colors, the output is the same as the plain one.
+	var plain bytes.Buffer
+	s.conf.HelpFormatter = nil
+	writeHelpMessage(s, &plain, false)
+	stripped := strings.NewReplacer(ansiBold, "", ansiCyan, "", ansiReset, "").Replace(out)
+	assert.Equal(t, plain.String(), stripped)
+}
+
+func TestNewHelp_SecretDefault(t *testing.T) {
+	config := struct {
+		Password string `opts:"secret" default:"changeme"`
+	}{}
+	help, err := NewHelp(&config, Conf{HelpShowValues: true}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "<redacted>", help.Options[0].Default)
+
+	var buf bytes.Buffer
+	require.NoError(t, HelpFormatterPlain.FormatHelp(&buf, help))
+	assert.Contains(t, buf.String(), "(default <redacted>) (current <redacted>)")
+	require.NoError(t, HelpFormatterMarkdown.FormatHelp(&buf, help))
+	assert.Contains(t, buf.String(), "| `--password string` | `<redacted>` |")
+	assert.NotContains(t, buf.String(), "changeme")
+}
+
+func TestHelpFormatter_Markdown(t *testing.T) {
+	config := struct {
+		Name     string `default:"app" desc:"the name"`
+		Database struct {
+			Host string `desc:"the host | port"`
+		} `desc:"Database options"`
+	}{}
+	s := &setup{conf: &Conf{
+		HelpMessage:   "Usage:",
+		HelpShowEnv:   true,
+		HelpFormatter: HelpFormatterMarkdown,
+		HelpDisable:   true,
+	}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+
+	var buf bytes.Buffer
+	writeHelpMessage(s, &buf, false)
+	assert.Equal(t, "Usage:\n"+
+		"\n"+
+		"| Flag | Default | Description | Environment |\n"+
+		"| --- | --- | --- | --- |\n"+
+		"| `--name string` | `app` | the name | `NAME` |\n"+
+		"\n"+
+		"## Database options\n"+
+		"\n"+
+		"| Flag | Default | Description | Environment |\n"+
+		"| --- | --- | --- | --- |\n"+
+		"| `--database.host string` |  | the host \\| port | `DATABASE_HOST` |\n",
+		buf.String())
+}
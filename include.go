@@ -0,0 +1,239 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// decodeFile decodes the content of the config file at path into a map and
+// finds the positions of its keys.  Files included using the include key are
+// decoded as well and merged into the result.  The stack contains the paths
+// of the files that are including this one and is used to detect cycles.
+// The decoder from the conf is only used if useConfDecoder is set, which is
+// the case for the config file itself but not for the files it includes or
+// the files in the config directory, which can have other formats.
+func decodeFile(s *setup, path string, content []byte, stack []string,
+	useConfDecoder bool) (map[string]interface{}, map[string]Position, error) {
+	var decoder FileDecoderFn
+	var positionsFn FilePositionsFn
+	if useConfDecoder {
+		decoder, positionsFn = s.conf.FileDecoder, s.conf.FilePositions
+	}
+	sniffed, tryAll := false, false
+	if decoder == nil {
+		// Look for the config file extension to determine the encoding and
+		// look at the content if that doesn't help.
+		decoder = lookupDecoder(filepath.Ext(path))
+		if decoder == nil {
+			decoder = sniffDecoder(content)
+			sniffed = decoder != nil
+		}
+		if decoder == nil {
+			decoder, tryAll = DecoderTryAll, true
+		}
+	}
+
+	m, err := decoder(content)
+	if err != nil && sniffed {
+		// The guess of the format can be wrong, so try all decoders before
+		// giving up.  The error of the guessed format is the most useful one
+		// if that fails too.
+		if tryAllM, tryAllErr := DecoderTryAll(content); tryAllErr == nil {
+			m, err, tryAll = tryAllM, nil, true
+		}
+	}
+	if err != nil {
+		return nil, nil, &DecodeError{path, err}
+	}
+	if positionsFn == nil {
+		if tryAll {
+			positionsFn = positionsTryAll
+		} else {
+			positionsFn = builtinPositions(decoder)
+		}
+	}
+	if m == nil {
+		m = make(map[string]interface{})
+	}
+
+	positions := filePositions(path, positionsFn, content)
+	if positions == nil {
+		positions = make(map[string]Position)
+	}
+
+	if s.conf.FileIncludeKey == "" {
+		return m, positions, nil
+	}
+	includes, set := m[s.conf.FileIncludeKey]
+	if !set {
+		return m, positions, nil
+	}
+	delete(m, s.conf.FileIncludeKey)
+
+	paths, err := includePaths(path, includes)
+	if err != nil {
+		return nil, nil, &DecodeError{path, err}
+	}
+
+	// Included files are merged first so that the values in the including
+	// file take precedence.
+	stack = append(stack[:len(stack):len(stack)], path)
+	result, resultPositions := make(map[string]interface{}), make(map[string]Position)
+	for _, include := range paths {
+		for _, p := range stack {
+			if p == include {
+				return nil, nil, fmt.Errorf("include cycle: %v -> %v",
+					strings.Join(stack, " -> "), include)
+			}
+		}
+
+		im, ipositions, err := readAndDecodeFile(s, include, stack, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		mergeMaps(result, im)
+		mergePositions(resultPositions, ipositions)
+	}
+	mergeMaps(result, m)
+	mergePositions(resultPositions, positions)
+
+	return result, resultPositions, nil
+}
+
+// readAndDecodeFile reads and decodes the config file at path, see decodeFile.
+func readAndDecodeFile(s *setup, path string, stack []string,
+	useConfDecoder bool) (map[string]interface{}, map[string]Position, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil, &FileNotFoundError{Path: path}
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("error reading config file at %v: %v", path, err)
+	}
+
+	return decodeFile(s, path, content, stack, useConfDecoder)
+}
+
+// includePaths returns the absolute paths of the files referred to by the
+// value of the include key.  The value can be a single path or a list of
+// paths.  Relative paths are relative to the directory of the including file
+// and paths can contain glob patterns.
+func includePaths(path string, includes interface{}) ([]string, error) {
+	var patterns []string
+	switch v := includes.(type) {
+	case string:
+		patterns = []string{v}
+	case []interface{}:
+		for _, elem := range v {
+			str, ok := elem.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid include of type %v",
+					reflect.TypeOf(elem))
+			}
+			patterns = append(patterns, str)
+		}
+	default:
+		return nil, fmt.Errorf("invalid include of type %v", reflect.TypeOf(includes))
+	}
+
+	var paths []string
+	for _, pattern := range patterns {
+		pattern = resolvePath(filepath.Dir(path), pattern)
+		if !strings.ContainsAny(pattern, "*?[") {
+			paths = append(paths, pattern)
+			continue
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %v: %v", pattern, err)
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+// resolvePath makes path absolute by resolving it relative to dir.
+func resolvePath(dir, path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path
+}
+
+// decodeDirectory decodes all config files in the config directory in lexical
+// order and merges them.  If FileDirectoryPattern is empty, only the files
+// with an extension for which a decoder is registered are used.  A missing
+// directory is ignored.
+func decodeDirectory(s *setup, dir string) (map[string]interface{}, map[string]Position, error) {
+	m, positions := make(map[string]interface{}), make(map[string]Position)
+
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return m, positions, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("error reading config directory at %v: %v", dir, err)
+	}
+
+	// ReadDir returns the entries sorted by filename.
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if s.conf.FileDirectoryPattern == "" {
+			if lookupDecoder(filepath.Ext(name)) == nil {
+				continue
+			}
+		} else if match, err := filepath.Match(s.conf.FileDirectoryPattern, name); err != nil {
+			return nil, nil, fmt.Errorf("invalid config directory pattern %v: %v",
+				s.conf.FileDirectoryPattern, err)
+		} else if !match {
+			continue
+		}
+
+		fm, fpositions, err := readAndDecodeFile(s, filepath.Join(dir, name), nil, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		mergeMaps(m, fm)
+		mergePositions(positions, fpositions)
+	}
+
+	return m, positions, nil
+}
+
+// mergeMaps deep-merges src into dst.  Nested maps are merged recursively,
+// all other values in src replace the values in dst.
+func mergeMaps(dst, src map[string]interface{}) {
+	for key, val := range src {
+		srcMap, srcIsMap := val.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			merged := make(map[string]interface{}, len(dstMap))
+			mergeMaps(merged, dstMap)
+			mergeMaps(merged, srcMap)
+			dst[key] = merged
+		} else {
+			dst[key] = val
+		}
+	}
+}
+
+// mergePositions adds the positions in src to dst, replacing the positions of
+// keys that are already in dst.
+func mergePositions(dst, src map[string]Position) {
+	for key, pos := range src {
+		dst[key] = pos
+	}
+}
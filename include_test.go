@@ -0,0 +1,210 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles creates the given files with their content in a new temporary
+// directory and returns its path.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "gonfig")
+	require.NoError(t, err)
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+type includeConfig struct {
+	Name     string
+	Port     int
+	Database struct {
+		Host string
+		User string
+		Pool int
+	}
+	Tags []string
+}
+
+func TestMergeMaps(t *testing.T) {
+	dst := map[string]interface{}{
+		"a": 1,
+		"b": map[string]interface{}{"c": 2, "d": 3},
+		"e": []interface{}{1},
+	}
+	mergeMaps(dst, map[string]interface{}{
+		"b": map[string]interface{}{"d": 4},
+		"e": []interface{}{2},
+		"f": 5,
+	})
+	assert.Equal(t, map[string]interface{}{
+		"a": 1,
+		"b": map[string]interface{}{"c": 2, "d": 4},
+		"e": []interface{}{2},
+		"f": 5,
+	}, dst)
+}
+
+func TestLoad_Include(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app.yaml": "include: [base.json, 'extra/*.toml']\n" +
+			"name: app\n" +
+			"database:\n  user: admin\n",
+		"base.json":         `{"name": "base", "port": 80, "database": {"host": "db", "user": "root"}}`,
+		"extra/b.toml":      "port = 8080\n",
+		"extra/a.toml":      "port = 8000\ntags = [\"a\"]\n",
+		"extra/ignored.txt": "port: 1\n",
+	})
+	defer os.RemoveAll(dir)
+	setOS(nil, nil)
+
+	config := includeConfig{}
+	require.NoError(t, Load(&config, Conf{
+		FileDefaultFilename: filepath.Join(dir, "app.yaml"),
+		FileIncludeKey:      "include",
+		FileStrict:          true,
+	}))
+	assert.Equal(t, "app", config.Name)
+	assert.Equal(t, 8080, config.Port)
+	assert.Equal(t, "db", config.Database.Host)
+	assert.Equal(t, "admin", config.Database.User)
+	assert.Equal(t, []string{"a"}, config.Tags)
+}
+
+func TestLoad_IncludeWithFileDecoder(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app":            "include: other.toml\nname: app\n",
+		"other.toml":     "port = 80\n",
+		"conf.d/10.json": `{"database": {"host": "db"}}`,
+	})
+	defer os.RemoveAll(dir)
+	setOS(nil, nil)
+
+	// The explicit decoder is only used for the config file itself.
+	config := includeConfig{}
+	require.NoError(t, Load(&config, Conf{
+		FileDefaultFilename: filepath.Join(dir, "app"),
+		FileDecoder:         DecoderYAML,
+		FileIncludeKey:      "include",
+		FileDirectory:       "conf.d",
+	}))
+	assert.Equal(t, "app", config.Name)
+	assert.Equal(t, 80, config.Port)
+	assert.Equal(t, "db", config.Database.Host)
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yaml":       "include: b.yaml\n",
+		"b.yaml":       "include: sub/c.yaml\n",
+		"sub/c.yaml":   "include: ../a.yaml\n",
+		"missing.yaml": "include: nope.yaml\n",
+		"invalid.yaml": "include: [b.yaml, 1]\n",
+		"port.yaml":    "include: bad.json\n",
+		"bad.json":     "{\n  \"port\": \"x\"\n}\n",
+	})
+	defer os.RemoveAll(dir)
+	setOS(nil, nil)
+
+	load := func(name string) error {
+		config := includeConfig{}
+		return Load(&config, Conf{
+			FileDefaultFilename: filepath.Join(dir, name),
+			FileIncludeKey:      "include",
+		})
+	}
+
+	err := load("a.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "include cycle")
+
+	err = load("missing.yaml")
+	var notFound *FileNotFoundError
+	require.True(t, errors.As(err, &notFound))
+	assert.Equal(t, filepath.Join(dir, "nope.yaml"), notFound.Path)
+
+	err = load("invalid.yaml")
+	var decodeErr *DecodeError
+	require.True(t, errors.As(err, &decodeErr))
+
+	// Errors refer to the position in the included file.
+	err = load("port.yaml")
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, filepath.Join(dir, "bad.json"), parseErr.Position.Filename)
+	assert.Equal(t, 2, parseErr.Position.Line)
+}
+
+func TestLoad_FileDirectory(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app.yaml":           "name: app\nport: 1\n",
+		"conf.d/20-db.json":  `{"database": {"host": "db2", "pool": 5}}`,
+		"conf.d/10-db.yaml":  "database:\n  host: db1\n  user: admin\n",
+		"conf.d/30-port.ini": "port = 3\n",
+		"conf.d/README":      "not a config file",
+		"conf.d/.hidden.yml": "port: 4\n",
+	})
+	defer os.RemoveAll(dir)
+	setOS(nil, nil)
+
+	config := includeConfig{}
+	require.NoError(t, Load(&config, Conf{
+		FileDefaultFilename: filepath.Join(dir, "app.yaml"),
+		FileDirectory:       "conf.d",
+	}))
+	assert.Equal(t, "app", config.Name)
+	assert.Equal(t, 3, config.Port)
+	assert.Equal(t, "db2", config.Database.Host)
+	assert.Equal(t, "admin", config.Database.User)
+	assert.Equal(t, 5, config.Database.Pool)
+
+	// The pattern restricts the files that are used and the directory is
+	// used even if the config file does not exist.
+	config = includeConfig{}
+	require.NoError(t, Load(&config, Conf{
+		FileDefaultFilename:  filepath.Join(dir, "doesntexist.yaml"),
+		FileDirectory:        filepath.Join(dir, "conf.d"),
+		FileDirectoryPattern: "*.yaml",
+	}))
+	assert.Equal(t, 0, config.Port)
+	assert.Equal(t, "db1", config.Database.Host)
+
+	// A missing directory is ignored.
+	require.NoError(t, Load(&config, Conf{
+		FileDefaultFilename: filepath.Join(dir, "app.yaml"),
+		FileDirectory:       "doesntexist.d",
+	}))
+}
+
+func TestLoad_FileDirectoryUnknownKeys(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app.yaml":       "name: app\n",
+		"conf.d/10.yaml": "port: 1\ndatabase:\n  host: db\nnmae: typo\n",
+	})
+	defer os.RemoveAll(dir)
+	setOS(nil, nil)
+
+	// Unknown keys are reported with the file they are in.
+	config := includeConfig{}
+	err := Load(&config, Conf{
+		FileDefaultFilename: filepath.Join(dir, "app.yaml"),
+		FileDirectory:       "conf.d",
+		FileStrict:          true,
+	})
+	var unknownErr *UnknownKeyError
+	require.True(t, errors.As(err, &unknownErr))
+	assert.Contains(t, err.Error(), "nmae ("+filepath.Join(dir, "conf.d", "10.yaml")+":4:1)")
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DecoderINI is the INI decoding function for config files.
+//
+// Keys before the first section header are top-level keys.  Sections map onto
+// nested structs, where dotted section names like [database.pool] are used
+// for deeper nesting.  Lines starting with ; or # are comments.  Values can
+// be quoted with double quotes (allowing Go escape sequences) or single
+// quotes (taken literally).  Unquoted values end at an inline comment.
+// Keys that are repeated within the same section produce a slice.
+//
+// All values are decoded as strings, which are parsed into the type of the
+// config variable like values from environment variables.
+var DecoderINI FileDecoderFn = func(c []byte) (map[string]interface{}, error) {
+	m, _, err := parseINI(c)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing INI config file: %v", err)
+	}
+	return m, nil
+}
+
+// PositionsINI is the FilePositionsFn for INI config files.
+var PositionsINI FilePositionsFn = func(c []byte) (map[string]Position, error) {
+	_, positions, err := parseINI(c)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing INI config file: %v", err)
+	}
+	return positions, nil
+}
+
+// parseINI parses the INI content into a map and the positions of its keys.
+func parseINI(c []byte) (map[string]interface{}, map[string]Position, error) {
+	result := make(map[string]interface{})
+	positions := make(map[string]Position)
+
+	section, prefix := result, ""
+	for i, line := range strings.Split(string(c), "\n") {
+		lineNb := i + 1
+		line = strings.TrimSuffix(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == ';' || trimmed[0] == '#' {
+			continue
+		}
+
+		if trimmed[0] == '[' {
+			if !strings.HasSuffix(trimmed, "]") {
+				return nil, nil, fmt.Errorf(
+					"line %v: invalid section header: %v", lineNb, trimmed)
+			}
+			name := strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if name == "" {
+				return nil, nil, fmt.Errorf("line %v: empty section name", lineNb)
+			}
+
+			section, prefix = result, ""
+			for _, part := range strings.Split(name, ".") {
+				part = strings.TrimSpace(part)
+				sub, ok := section[part].(map[string]interface{})
+				if !ok {
+					if _, exists := section[part]; exists {
+						return nil, nil, fmt.Errorf("line %v: section %v "+
+							"conflicts with key %v", lineNb, name, prefix+part)
+					}
+					sub = make(map[string]interface{})
+					section[part] = sub
+					positions[prefix+part] = Position{
+						Line:   lineNb,
+						Column: strings.Index(line, "[") + 1,
+					}
+				}
+				section, prefix = sub, prefix+part+"."
+			}
+			continue
+		}
+
+		var key, value string
+		if sep := strings.IndexAny(trimmed, "=:"); sep < 0 {
+			// A key without value is interpreted as a boolean flag.
+			key, value = trimmed, "true"
+		} else {
+			key = strings.TrimSpace(trimmed[:sep])
+			var err error
+			value, err = parseINIValue(strings.TrimSpace(trimmed[sep+1:]))
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %v: %v", lineNb, err)
+			}
+		}
+		if key == "" {
+			return nil, nil, fmt.Errorf("line %v: missing key", lineNb)
+		}
+
+		switch existing := section[key].(type) {
+		case nil:
+			section[key] = value
+			positions[prefix+key] = Position{
+				Line:   lineNb,
+				Column: strings.Index(line, key) + 1,
+			}
+		case string:
+			section[key] = []interface{}{existing, value}
+		case []interface{}:
+			section[key] = append(existing, value)
+		default:
+			return nil, nil, fmt.Errorf("line %v: key %v conflicts with section",
+				lineNb, prefix+key)
+		}
+	}
+
+	return result, positions, nil
+}
+
+// parseINIValue interprets the raw value of an INI line by removing quotes
+// or trailing comments.
+func parseINIValue(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+
+	var value, rest string
+	switch raw[0] {
+	case '"':
+		end := 1
+		for ; end < len(raw); end++ {
+			if raw[end] == '\\' {
+				end++
+			} else if raw[end] == '"' {
+				break
+			}
+		}
+		if end >= len(raw) {
+			return "", fmt.Errorf("unterminated quoted value: %v", raw)
+		}
+		unquoted, err := strconv.Unquote(raw[:end+1])
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted value %v: %v", raw[:end+1], err)
+		}
+		value, rest = unquoted, raw[end+1:]
+
+	case '\'':
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted value: %v", raw)
+		}
+		value, rest = raw[1:end+1], raw[end+2:]
+
+	default:
+		// Inline comments have to be preceded by whitespace.
+		for i := 1; i < len(raw); i++ {
+			if (raw[i] == ';' || raw[i] == '#') && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+				return strings.TrimSpace(raw[:i]), nil
+			}
+		}
+		return raw, nil
+	}
+
+	rest = strings.TrimSpace(rest)
+	if rest != "" && rest[0] != ';' && rest[0] != '#' {
+		return "", fmt.Errorf("unexpected content after quoted value: %v", rest)
+	}
+	return value, nil
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoderINI(t *testing.T) {
+	m, err := DecoderINI([]byte(`; global settings
+name = my app ; inline comment
+debug
+
+[database]
+# the host
+host = "db.local"
+password = 'p;a#ss'
+port: 5432
+
+[database.pool]
+size = 10
+hosts = a
+hosts = b
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":  "my app",
+		"debug": "true",
+		"database": map[string]interface{}{
+			"host":     "db.local",
+			"password": "p;a#ss",
+			"port":     "5432",
+			"pool": map[string]interface{}{
+				"size":  "10",
+				"hosts": []interface{}{"a", "b"},
+			},
+		},
+	}, m)
+}
+
+func TestDecoderINI_Invalid(t *testing.T) {
+	for _, content := range []string{
+		"[section\nkey = value\n",
+		"[]\n",
+		"= value\n",
+		"key = \"unterminated\n",
+		"key = 'unterminated\n",
+		"key = \"quoted\" trailing\n",
+		"key = value\n[key]\n",
+		"[section]\nsection = 1\n[section.section]\n",
+	} {
+		_, err := DecoderINI([]byte(content))
+		assert.Error(t, err, content)
+	}
+}
+
+func TestLoad_INI(t *testing.T) {
+	config := struct {
+		Name     string
+		Debug    bool
+		Database struct {
+			Host string
+			Port int
+			Pool struct {
+				Size  int
+				Ports []int
+			}
+		}
+	}{}
+
+	s := &setup{configFilePath: "/etc/app.conf", conf: &Conf{}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+	require.NoError(t, parseFileContent(s, []byte(`name = app
+debug = true
+[database]
+host = db
+port = 5432
+[database.pool]
+size = 3
+ports = 1
+ports = 2
+`)))
+	assert.Equal(t, "app", config.Name)
+	assert.True(t, config.Debug)
+	assert.Equal(t, "db", config.Database.Host)
+	assert.Equal(t, 5432, config.Database.Port)
+	assert.Equal(t, 3, config.Database.Pool.Size)
+	assert.Equal(t, []int{1, 2}, config.Database.Pool.Ports)
+
+	err := parseFileContent(s, []byte("[database]\nport = abc\n"))
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, Position{"/etc/app.conf", 2, 1}, parseErr.Position)
+}
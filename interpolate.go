@@ -0,0 +1,235 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// initInterpolation collects the default values of all options so that they
+// can be referenced in interpolations.
+func initInterpolation(s *setup) {
+	s.interpolationValues = make(map[string]string)
+	for _, opt := range s.allOpts {
+		if opt.defaultSet && !opt.isParent {
+			s.interpolationValues[opt.fullID()] = opt.defaul
+		}
+	}
+}
+
+// addInterpolationValues adds the simple values from the map, which is
+// structured like the config struct, to the values that can be referenced in
+// interpolations.  They take precedence over the default values.
+func addInterpolationValues(s *setup, m map[string]interface{}, opts []*option) {
+	for _, opt := range opts {
+		val, set := m[opt.id]
+		if !set {
+			continue
+		}
+
+		if casted, ok := val.(map[string]interface{}); ok && opt.isParent {
+			addInterpolationValues(s, casted, opt.subOpts)
+		} else if val != nil && isSimpleType(reflect.TypeOf(val)) {
+			s.interpolationValues[opt.fullID()] = fmt.Sprint(val)
+		}
+	}
+}
+
+// reapplyDefaults sets the default values that reference other options again
+// for the options that are still at their default value, now that the values
+// from the config file or map have been added to the interpolation values.
+func reapplyDefaults(s *setup, source Source) error {
+	var errs LoadErrors
+	for _, opt := range s.allOpts {
+		// The default value is only valid if it has been set.
+		if !opt.defaultValue.IsValid() || opt.source != "" ||
+			!strings.Contains(opt.defaul, "$") {
+			continue
+		}
+
+		if err := setDefault(s, opt); err != nil {
+			errs = appendError(errs, &OptionError{
+				OptionID: opt.fullID(),
+				Source:   source,
+				Err:      err,
+			})
+		}
+	}
+	return errs.errorOrNil()
+}
+
+// interpolateMap returns a copy of the map, which is structured like the
+// config struct, in which the variables in all string values of the options
+// are expanded.  Values that can't be expanded are left out of the result and
+// an error is returned for them.
+func interpolateMap(s *setup, m map[string]interface{}, opts []*option,
+	source Source) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(m))
+	for key, val := range m {
+		result[key] = val
+	}
+
+	var errs LoadErrors
+	for _, opt := range opts {
+		val, set := m[opt.id]
+		if !set {
+			continue
+		}
+
+		if casted, ok := val.(map[string]interface{}); ok && opt.isParent {
+			sub, err := interpolateMap(s, casted, opt.subOpts, source)
+			errs = appendError(errs, err)
+			result[opt.id] = sub
+			continue
+		}
+
+		expanded, err := interpolateValue(s, opt, val)
+		if err != nil {
+			delete(result, opt.id)
+			errs = appendError(errs, &OptionError{
+				OptionID: opt.fullID(),
+				Source:   source,
+				Err:      err,
+			})
+			continue
+		}
+		result[opt.id] = expanded
+	}
+
+	return result, errs.errorOrNil()
+}
+
+// interpolateValue expands the variables in a decoded value of the option.
+// Strings are expanded, as well as the strings in lists and maps.
+func interpolateValue(s *setup, opt *option, val interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case string:
+		return interpolate(s, v, []string{opt.fullID()})
+
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, elem := range v {
+			expanded, err := interpolateValue(s, opt, elem)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = expanded
+		}
+		return result, nil
+
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			expanded, err := interpolateValue(s, opt, elem)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = expanded
+		}
+		return result, nil
+
+	default:
+		return val, nil
+	}
+}
+
+// interpolate expands the variables in value.  Variables are written as
+// ${NAME} or ${NAME:-default}.  NAME is either the full ID of a config option,
+// like database.host, or the name of an environment variable.  The default is
+// used when the variable is not set or empty and can contain variables
+// itself.  A literal $ is written as $$.
+//
+// The stack contains the full IDs of the options that are being expanded and
+// is used to detect reference cycles.
+func interpolate(s *setup, value string, stack []string) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 >= len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+
+		switch value[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+
+		case '{':
+			end := closingBrace(value, i+1)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable in '%v'", value)
+			}
+			expanded, err := expandVariable(s, value[i+2:end], stack)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(expanded)
+			i = end
+
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), nil
+}
+
+// closingBrace returns the index of the brace that closes the brace at index
+// start in s, or -1 if there is none.
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// expandVariable expands a single variable of the form NAME or NAME:-default.
+func expandVariable(s *setup, variable string, stack []string) (string, error) {
+	name, def, hasDefault := variable, "", false
+	if idx := strings.Index(variable, ":-"); idx >= 0 {
+		name, def, hasDefault = variable[:idx], variable[idx+2:], true
+	}
+	if name == "" {
+		return "", fmt.Errorf("empty variable name in '${%v}'", variable)
+	}
+
+	var value string
+	if raw, ok := s.interpolationValues[name]; ok {
+		for _, id := range stack {
+			if id == name {
+				return "", fmt.Errorf("reference cycle: %v -> %v",
+					strings.Join(stack, " -> "), name)
+			}
+		}
+		// Avoid sharing the backing array of the stack.
+		expanded, err := interpolate(s, raw, append(stack[:len(stack):len(stack)], name))
+		if err != nil {
+			return "", err
+		}
+		value = expanded
+	} else {
+		value = os.Getenv(name)
+	}
+
+	if value == "" && hasDefault {
+		return interpolate(s, def, stack)
+	}
+	return value, nil
+}
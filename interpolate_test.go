@@ -0,0 +1,167 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolate(t *testing.T) {
+	setOS(nil, map[string]string{"HOME": "/home/me", "EMPTY": ""})
+	s := &setup{interpolationValues: map[string]string{
+		"database.host": "db",
+		"database.port": "5432",
+		"database.url":  "${database.host}:${database.port}",
+		"a":             "${b}",
+		"b":             "${a}",
+	}}
+
+	var tests = []struct {
+		value    string
+		expected string
+		err      bool
+	}{
+		{"plain", "plain", false},
+		{"${HOME}/data", "/home/me/data", false},
+		{"${APP_ENV:-dev}", "dev", false},
+		{"${EMPTY:-dev}", "dev", false},
+		{"${HOME:-dev}", "/home/me", false},
+		{"${UNSET}", "", false},
+		{"${APP_ENV:-${HOME}}", "/home/me", false},
+		{"${database.host}:${database.port}", "db:5432", false},
+		{"postgres://${database.url}", "postgres://db:5432", false},
+		{"$$HOME costs $5", "$HOME costs $5", false},
+		{"$${HOME}", "${HOME}", false},
+		{"${a}", "", true},
+		{"${HOME", "", true},
+		{"${:-x}", "", true},
+	}
+
+	for _, test := range tests {
+		result, err := interpolate(s, test.value, []string{"x"})
+		if test.err {
+			assert.Error(t, err, test.value)
+			continue
+		}
+		if assert.NoError(t, err, test.value) {
+			assert.Equal(t, test.expected, result, test.value)
+		}
+	}
+
+	_, err := interpolate(s, "${x}", []string{"x"})
+	assert.NoError(t, err, "unknown option IDs are environment variables")
+	s.interpolationValues["x"] = "${x}"
+	_, err = interpolate(s, "${x}", []string{"x"})
+	assert.EqualError(t, err, "reference cycle: x -> x")
+}
+
+func TestLoad_Interpolate(t *testing.T) {
+	setOS(nil, map[string]string{
+		"HOME":     "/home/me",
+		"APP_NAME": "${database.name}-app",
+	})
+
+	config := struct {
+		Name     string
+		DataDir  string `id:"datadir" default:"${HOME}/data"`
+		Env      string `default:"${APP_ENV:-dev}"`
+		Database struct {
+			Name string `default:"${env}db"`
+			Host string
+			Port int
+			URL  string `id:"url"`
+		}
+		Tags []string
+	}{}
+
+	content := []byte(`{
+		"database": {
+			"host": "db.local",
+			"port": "${DB_PORT:-5432}",
+			"url": "${database.host}:${database.port}/${database.name}"
+		},
+		"tags": ["${env}", "$$literal"]
+	}`)
+	require.NoError(t, LoadWithRawFile(&config, content, Conf{
+		FileDecoder:    DecoderJSON,
+		FlagDisable:    true,
+		EnvPrefix:      "APP_",
+		Interpolate:    true,
+		InterpolateEnv: true,
+	}))
+	assert.Equal(t, "/home/me/data", config.DataDir)
+	assert.Equal(t, "dev", config.Env)
+	assert.Equal(t, "devdb", config.Database.Name)
+	assert.Equal(t, 5432, config.Database.Port)
+	assert.Equal(t, "db.local:5432/devdb", config.Database.URL)
+	assert.Equal(t, []string{"dev", "$literal"}, config.Tags)
+	assert.Equal(t, "devdb-app", config.Name)
+
+	// Without interpolation, values are taken literally.
+	config.Database.URL = ""
+	require.NoError(t, LoadRawFile(&config, []byte(`{"database": {"url": "${x}"}}`),
+		Conf{FileDecoder: DecoderJSON}))
+	assert.Equal(t, "${x}", config.Database.URL)
+}
+
+func TestLoad_InterpolateDefaults(t *testing.T) {
+	setOS(nil, nil)
+
+	type defaultsConfig struct {
+		Host string `default:"localhost"`
+		Base int    `default:"8000"`
+		Port int    `default:"${base}"`
+		URL  string `id:"url" default:"http://${host}:${port}"`
+	}
+	conf := Conf{FileDecoder: DecoderYAML, Interpolate: true}
+
+	// Defaults that reference other options use the values from the file.
+	config := defaultsConfig{}
+	require.NoError(t, LoadRawFile(&config, []byte("host: example.com\n"), conf))
+	assert.Equal(t, "http://example.com:8000", config.URL)
+
+	config = defaultsConfig{}
+	require.NoError(t, LoadMap(&config, map[string]interface{}{"base": 9000},
+		Conf{Interpolate: true}))
+	assert.Equal(t, 9000, config.Port)
+	assert.Equal(t, "http://localhost:9000", config.URL)
+
+	// Options that are set keep their value.
+	config = defaultsConfig{}
+	require.NoError(t, LoadRawFile(&config,
+		[]byte("host: example.com\nurl: http://other\n"), conf))
+	assert.Equal(t, "http://other", config.URL)
+
+	// A default that becomes invalid is reported for the source.
+	config = defaultsConfig{}
+	err := LoadRawFile(&config, []byte("base: x\n"), conf)
+	assert.Contains(t, err.Error(), "option 'port' from config file: "+
+		"error parsing default value")
+}
+
+func TestLoad_InterpolateCycle(t *testing.T) {
+	setOS(nil, nil)
+
+	config := struct {
+		A string
+		B string
+		C string
+	}{}
+	err := LoadRawFile(&config, []byte("a: ${b}\nb: ${a}\nc: ok\n"), Conf{
+		FileDecoder: DecoderYAML,
+		Interpolate: true,
+	})
+	require.Error(t, err)
+
+	var optErr *OptionError
+	require.True(t, errors.As(err, &optErr))
+	assert.Contains(t, optErr.Error(), "reference cycle")
+	assert.Equal(t, 1, optErr.Position.Line)
+	assert.Equal(t, "ok", config.C)
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// DecoderJSON5 is the decoding function for config files in JSON with the
+// following extensions from JSON5:
+//  - // line comments and /* */ block comments
+//  - trailing commas in objects and arrays
+//  - unquoted object keys
+//  - single-quoted strings
+// It produces the same types as DecoderJSON.
+var DecoderJSON5 FileDecoderFn = func(c []byte) (map[string]interface{}, error) {
+	converted, offsets, err := json5ToJSON(c)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing JSON5 config file: %v", err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(converted, &m); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) && int(syntaxErr.Offset) < len(offsets) {
+			pos := offsetPosition(c, offsets[syntaxErr.Offset])
+			return nil, fmt.Errorf("error parsing JSON5 config file at %v: %v",
+				pos, err)
+		}
+		return nil, fmt.Errorf("error parsing JSON5 config file: %v", err)
+	}
+
+	return m, nil
+}
+
+// DecoderJSONC is the decoding function for JSON with comments.  It is the
+// same as DecoderJSON5, which also accepts comments.
+var DecoderJSONC = DecoderJSON5
+
+// PositionsJSON5 is the FilePositionsFn for JSON5 config files.
+var PositionsJSON5 FilePositionsFn = func(c []byte) (map[string]Position, error) {
+	converted, offsets, err := json5ToJSON(c)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing JSON5 config file: %v", err)
+	}
+
+	keyOffsets, err := jsonKeyOffsets(converted)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing JSON5 config file: %v", err)
+	}
+
+	positions := make(map[string]Position, len(keyOffsets))
+	for key, offset := range keyOffsets {
+		positions[key] = offsetPosition(c, offsets[offset])
+	}
+	return positions, nil
+}
+
+// isIdentByte returns whether c can be used in an unquoted key.
+func isIdentByte(c byte, first bool) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+		!first && c >= '0' && c <= '9' || c >= 0x80
+}
+
+// skipJSON5Space returns the offset of the first byte from offset i that is
+// not whitespace or part of a comment.
+func skipJSON5Space(c []byte, i int) int {
+	for i < len(c) {
+		switch {
+		case c[i] == ' ' || c[i] == '\t' || c[i] == '\n' || c[i] == '\r':
+			i++
+		case c[i] == '/' && i+1 < len(c) && c[i+1] == '/':
+			for i < len(c) && c[i] != '\n' {
+				i++
+			}
+		case c[i] == '/' && i+1 < len(c) && c[i+1] == '*':
+			i += 2
+			for i+1 < len(c) && !(c[i] == '*' && c[i+1] == '/') {
+				i++
+			}
+			i += 2
+		default:
+			return i
+		}
+	}
+	return len(c)
+}
+
+// json5ToJSON converts JSON5 content into plain JSON.  Next to the converted
+// content, it returns for every byte of the converted content the offset in
+// the original content it originates from.
+func json5ToJSON(c []byte) ([]byte, []int, error) {
+	out := make([]byte, 0, len(c))
+	offsets := make([]int, 0, len(c)+1)
+	write := func(offset int, bs ...byte) {
+		for _, b := range bs {
+			out = append(out, b)
+			offsets = append(offsets, offset)
+		}
+	}
+
+	for i := 0; i < len(c); {
+		switch ch := c[i]; {
+		case ch == '/' && i+1 < len(c) && (c[i+1] == '/' || c[i+1] == '*'):
+			if c[i+1] == '*' && !bytes.Contains(c[i+2:], []byte("*/")) {
+				return nil, nil, fmt.Errorf("unterminated comment at %v",
+					offsetPosition(c, i))
+			}
+			// Replace the comment with a single space.
+			next := skipJSON5Space(c, i)
+			write(i, ' ')
+			i = next
+
+		case ch == '"':
+			start := i
+			write(i, '"')
+			for i++; i < len(c) && c[i] != '"'; i++ {
+				if c[i] == '\\' && i+1 < len(c) {
+					write(i, c[i])
+					i++
+				}
+				write(i, c[i])
+			}
+			if i >= len(c) {
+				return nil, nil, fmt.Errorf("unterminated string at %v",
+					offsetPosition(c, start))
+			}
+			write(i, '"')
+			i++
+
+		case ch == '\'':
+			start := i
+			write(i, '"')
+			for i++; i < len(c) && c[i] != '\''; i++ {
+				switch {
+				case c[i] == '\\' && i+1 < len(c) && c[i+1] == '\'':
+					// No need to escape single quotes in double quotes.
+					i++
+					write(i, '\'')
+				case c[i] == '\\' && i+1 < len(c):
+					write(i, c[i], c[i+1])
+					i++
+				case c[i] == '"':
+					write(i, '\\', '"')
+				default:
+					write(i, c[i])
+				}
+			}
+			if i >= len(c) {
+				return nil, nil, fmt.Errorf("unterminated string at %v",
+					offsetPosition(c, start))
+			}
+			write(i, '"')
+			i++
+
+		case ch == ',':
+			// Drop trailing commas.
+			if next := skipJSON5Space(c, i+1); next < len(c) &&
+				(c[next] == '}' || c[next] == ']') {
+				i++
+				continue
+			}
+			write(i, ',')
+			i++
+
+		case ch >= '0' && ch <= '9' || ch == '-':
+			// Copy numbers as a whole, so that exponents are not mistaken
+			// for identifiers.
+			end := i + 1
+			for end < len(c) && (isIdentByte(c[end], false) || c[end] == '.' ||
+				c[end] == '+' || c[end] == '-') {
+				end++
+			}
+			for j := i; j < end; j++ {
+				write(j, c[j])
+			}
+			i = end
+
+		case isIdentByte(ch, true):
+			end := i + 1
+			for end < len(c) && isIdentByte(c[end], false) {
+				end++
+			}
+			ident := string(c[i:end])
+			if next := skipJSON5Space(c, end); next < len(c) && c[next] == ':' {
+				// An unquoted key.
+				write(i, '"')
+				for j := i; j < end; j++ {
+					write(j, c[j])
+				}
+				write(end, '"')
+			} else if ident == "true" || ident == "false" || ident == "null" {
+				for j := i; j < end; j++ {
+					write(j, c[j])
+				}
+			} else {
+				return nil, nil, fmt.Errorf("unexpected identifier '%v' at %v",
+					ident, offsetPosition(c, i))
+			}
+			i = end
+
+		default:
+			write(i, ch)
+			i++
+		}
+	}
+	// Map the end of the content as well.
+	offsets = append(offsets, len(c))
+
+	return out, offsets, nil
+}
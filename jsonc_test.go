@@ -0,0 +1,69 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoderJSON5(t *testing.T) {
+	content := []byte(`// The config of the app.
+{
+	/* block
+	   comment */
+	name: 'my "app"', // trailing comment
+	"url": "http://host/path", 
+	ratio: 1.5e3,
+	neg: -2,
+	ok: true,
+	nothing: null,
+	list: [1, 2, 3,],
+	nested: {
+		key: 'it\'s',
+	},
+}
+`)
+	m, err := DecoderJSON5(content)
+	require.NoError(t, err)
+
+	expected, err := DecoderJSON([]byte(`{
+		"name": "my \"app\"",
+		"url": "http://host/path",
+		"ratio": 1500,
+		"neg": -2,
+		"ok": true,
+		"nothing": null,
+		"list": [1, 2, 3],
+		"nested": {"key": "it's"}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, expected, m)
+
+	positions, err := PositionsJSON5(content)
+	require.NoError(t, err)
+	assert.Equal(t, Position{Line: 5, Column: 2}, positions["name"])
+	assert.Equal(t, Position{Line: 6, Column: 2}, positions["url"])
+	assert.Equal(t, Position{Line: 13, Column: 3}, positions["nested.key"])
+}
+
+func TestDecoderJSON5_Invalid(t *testing.T) {
+	for _, content := range []string{
+		"{key: value}",
+		"{key: 'unterminated}",
+		"{\"key\": \"unterminated}",
+		"{key: 1 /* unterminated",
+		"{key: 1,, other: 2}",
+	} {
+		_, err := DecoderJSON5([]byte(content))
+		assert.Error(t, err, content)
+	}
+
+	_, err := DecoderJSON5([]byte("{\n  key: 1,\n  other 2\n}"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "at line 3")
+}
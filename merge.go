@@ -0,0 +1,83 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// checkMergeOpts checks that the merge opts flags of the option are valid for
+// its type.
+func checkMergeOpts(opt *option) error {
+	isSliceOpt := isSlice(opt.value)
+	count := 0
+	for _, flag := range []string{fieldOptAppend, fieldOptReplace, fieldOptMerge} {
+		if opt.hasFieldOpt(flag) {
+			count++
+		}
+	}
+	switch {
+	case count > 1:
+		return fmt.Errorf("option %v can only have one of the %v, %v and %v "+
+			"opts flags", opt.fullID(), fieldOptAppend, fieldOptReplace, fieldOptMerge)
+	case opt.hasFieldOpt(fieldOptAppend) && !isSliceOpt:
+		return fmt.Errorf("the %v opts flag can only be used for slices, "+
+			"option %v has type %v", fieldOptAppend, opt.fullID(), opt.value.Type())
+	case opt.hasFieldOpt(fieldOptMerge) && !opt.isMap:
+		return fmt.Errorf("the %v opts flag can only be used for maps, "+
+			"option %v has type %v", fieldOptMerge, opt.fullID(), opt.value.Type())
+	case opt.hasFieldOpt(fieldOptReplace) && !isSliceOpt && !opt.isMap:
+		return fmt.Errorf("the %v opts flag can only be used for slices and "+
+			"maps, option %v has type %v", fieldOptReplace, opt.fullID(),
+			opt.value.Type())
+	}
+	return nil
+}
+
+// setOptionValue sets the value of the option from the given source.  The set
+// function is called with a new value to fill in, which is then combined with
+// the current value of the option according to its merge policy:
+//  - slices are replaced, unless the option has the append opts flag, in
+//    which case the values of every source after the first one are appended
+//  - maps are merged, so that keys from later sources are added to the keys
+//    from earlier sources, unless the option has the replace opts flag, in
+//    which case every source replaces all keys of the earlier ones
+// Default values are always replaced.
+//
+// For maps, set can be called several times from the same source to set
+// individual keys.
+func setOptionValue(opt *option, source Source, set func(reflect.Value) error) error {
+	v := opt.value
+	switch {
+	case isSlice(v) && opt.hasFieldOpt(fieldOptAppend) && opt.source != "":
+		newValue := reflect.New(v.Type()).Elem()
+		if err := set(newValue); err != nil {
+			return err
+		}
+		v.Set(reflect.AppendSlice(v, newValue))
+
+	case opt.isMap:
+		newValue := reflect.New(v.Type()).Elem()
+		newValue.Set(reflect.MakeMap(v.Type()))
+		if err := set(newValue); err != nil {
+			return err
+		}
+		if v.IsNil() || opt.hasFieldOpt(fieldOptReplace) && opt.source != source {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for _, key := range newValue.MapKeys() {
+			v.SetMapIndex(key, newValue.MapIndex(key))
+		}
+
+	default:
+		if err := set(v); err != nil {
+			return err
+		}
+	}
+
+	opt.source = source
+	return nil
+}
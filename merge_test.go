@@ -0,0 +1,63 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckMergeOpts(t *testing.T) {
+	assert.Error(t, inspectConfigStructure(&setup{}, &struct {
+		Name string `opts:"append"`
+	}{}))
+	assert.Error(t, inspectConfigStructure(&setup{}, &struct {
+		Names []string `opts:"merge"`
+	}{}))
+	assert.Error(t, inspectConfigStructure(&setup{}, &struct {
+		Name string `opts:"replace"`
+	}{}))
+	assert.Error(t, inspectConfigStructure(&setup{}, &struct {
+		Names []string `opts:"append,replace"`
+	}{}))
+	assert.NoError(t, inspectConfigStructure(&setup{}, &struct {
+		Names  []string               `opts:"append"`
+		Others []string               `opts:"replace"`
+		Labels map[string]interface{} `opts:"replace"`
+		Extra  map[string]interface{} `opts:"merge"`
+	}{}))
+}
+
+func TestLoad_MergePolicies(t *testing.T) {
+	setOS([]string{"--plugins", "flag", "--labels.flag", "1", "--replaced.flag", "1"},
+		map[string]string{
+			"APP_PLUGINS":       "env1,env2",
+			"APP_TAGS":          "env",
+			"APP_LABELS_ENV":    "1",
+			"APP_REPLACED_ENV":  "1",
+			"APP_REPLACED_ENV2": "2",
+		})
+
+	config := struct {
+		Plugins  []string `opts:"append" default:"default"`
+		Tags     []string `default:"default"`
+		Labels   map[string]interface{}
+		Replaced map[string]interface{} `opts:"replace"`
+	}{}
+	require.NoError(t, LoadWithMap(&config, map[string]interface{}{
+		"plugins":  []interface{}{"file"},
+		"tags":     []interface{}{"file"},
+		"labels":   map[string]interface{}{"file": 1},
+		"replaced": map[string]interface{}{"file": 1},
+	}, Conf{EnvPrefix: "APP_"}))
+
+	assert.Equal(t, []string{"file", "env1", "env2", "flag"}, config.Plugins)
+	assert.Equal(t, []string{"env"}, config.Tags)
+	assert.Equal(t, map[string]interface{}{"file": 1, "env": "1", "flag": "1"},
+		config.Labels)
+	assert.Equal(t, map[string]interface{}{"flag": "1"}, config.Replaced)
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Path is a string type for config variables that hold a filesystem path.
+// Config variables of this type, or slices of it, are treated as if they had
+// the "path" opts flag.
+type Path string
+
+var typeOfPath = reflect.TypeOf(Path(""))
+
+// isPathOption returns whether the values of the option should be treated
+// as paths.
+func isPathOption(opt *option) bool {
+	if opt.isParent || opt.isMap {
+		return false
+	}
+	if opt.hasFieldOpt(fieldOptPath) || opt.hasFieldOpt(fieldOptPathExists) {
+		return true
+	}
+
+	t := opt.value.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t == typeOfPath
+}
+
+// expandPath expands a leading ~ in the path to the home directory of the
+// user and makes it absolute by resolving it relative to dir.  If dir is
+// empty, the path is resolved relative to the working directory.
+func expandPath(path, dir string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") ||
+		strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand %v: %v", path, err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	if !filepath.IsAbs(path) && dir != "" {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Abs(path)
+}
+
+// checkPathOpts checks that the path and exists opts flags are only used for
+// strings and slices of strings.
+func checkPathOpts(opt *option) error {
+	if !opt.hasFieldOpt(fieldOptPath) && !opt.hasFieldOpt(fieldOptPathExists) {
+		return nil
+	}
+
+	t := opt.value.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if opt.isParent || opt.isMap || t.Kind() != reflect.String {
+		return fmt.Errorf("the %v and %v opts flags can only be used for "+
+			"strings and slices of strings, option %v has type %v",
+			fieldOptPath, fieldOptPathExists, opt.fullID(), opt.value.Type())
+	}
+	return nil
+}
+
+// resolveOptionPaths expands the paths in the value of the path option in
+// place, resolving relative paths against dir.  If checkExists is set and the
+// option has the "exists" opts flag, it also checks that the paths exist.
+func resolveOptionPaths(opt *option, dir string, checkExists bool) error {
+	if !isPathOption(opt) {
+		return nil
+	}
+
+	v := opt.value
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	var values []reflect.Value
+	switch {
+	case v.Kind() == reflect.String:
+		values = []reflect.Value{v}
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, v.Index(i))
+		}
+	default:
+		return fmt.Errorf("path option of unsupported type %v", v.Type())
+	}
+
+	for _, val := range values {
+		path, err := expandPath(val.String(), dir)
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			continue
+		}
+		if checkExists && opt.hasFieldOpt(fieldOptPathExists) {
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("path %v does not exist", path)
+			}
+		}
+		val.SetString(path)
+	}
+
+	return nil
+}
+
+// resolveMapPaths resolves the paths of the path options that are set in the
+// map, which is structured like the config struct, against dir.
+func resolveMapPaths(m map[string]interface{}, opts []*option, dir string,
+	source Source) error {
+	var errs LoadErrors
+	for _, opt := range opts {
+		val, set := m[opt.id]
+		if !set {
+			continue
+		}
+
+		if casted, ok := val.(map[string]interface{}); ok && opt.isParent {
+			errs = appendError(errs, resolveMapPaths(casted, opt.subOpts, dir, source))
+		} else if err := resolveOptionPaths(opt, dir, true); err != nil {
+			errs = appendError(errs, &OptionError{
+				OptionID: opt.fullID(),
+				Source:   source,
+				Err:      err,
+			})
+		}
+	}
+
+	return errs.errorOrNil()
+}
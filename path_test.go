@@ -0,0 +1,112 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandPath(t *testing.T) {
+	setOS(nil, map[string]string{"HOME": "/home/me"})
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+
+	var tests = []struct {
+		path     string
+		dir      string
+		expected string
+	}{
+		{"", "/etc/app", ""},
+		{"/abs/path", "/etc/app", "/abs/path"},
+		{"certs/server.pem", "/etc/app", "/etc/app/certs/server.pem"},
+		{"../shared", "/etc/app", "/etc/shared"},
+		{"certs/server.pem", "", filepath.Join(cwd, "certs/server.pem")},
+		{"~", "/etc/app", "/home/me"},
+		{"~/data", "/etc/app", "/home/me/data"},
+		{"~user/data", "/etc/app", "/etc/app/~user/data"},
+	}
+
+	for _, test := range tests {
+		result, err := expandPath(test.path, test.dir)
+		if assert.NoError(t, err, test.path) {
+			assert.Equal(t, test.expected, result, test.path)
+		}
+	}
+}
+
+func TestCheckPathOpts(t *testing.T) {
+	err := inspectConfigStructure(&setup{}, &struct {
+		Port int `opts:"path"`
+	}{})
+	assert.EqualError(t, err, "the path and exists opts flags can only be "+
+		"used for strings and slices of strings, option port has type int")
+	assert.Error(t, inspectConfigStructure(&setup{}, &struct {
+		Dirs map[string]interface{} `opts:"exists"`
+	}{}))
+	assert.NoError(t, inspectConfigStructure(&setup{}, &struct {
+		Dir   string    `opts:"exists"`
+		Dirs  []string  `opts:"path"`
+		Other *string   `opts:"path"`
+		Paths *[]string `opts:"path"`
+		Typed Path      `opts:"exists"`
+	}{}))
+}
+
+func TestLoad_Paths(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app/app.yaml": "cert: certs/server.pem\n" +
+			"key: certs/server.key\n" +
+			"plugins: [plugins/a.so, /opt/b.so]\n" +
+			"name: some/name\n",
+		"app/certs/server.pem": "",
+	})
+	defer os.RemoveAll(dir)
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+
+	setOS([]string{"--logfile", "app.log"}, map[string]string{
+		"HOME":      "/home/me",
+		"APP_CACHE": "~/cache",
+	})
+
+	config := struct {
+		Cert    string `opts:"path,exists"`
+		Key     Path
+		Plugins []Path
+		Name    string
+		Cache   string `opts:"path"`
+		LogFile string `id:"logfile" opts:"path"`
+		Data    Path   `default:"~/data"`
+	}{}
+	require.NoError(t, Load(&config, Conf{
+		FileDefaultFilename: filepath.Join(dir, "app", "app.yaml"),
+		EnvPrefix:           "APP_",
+	}))
+	assert.Equal(t, filepath.Join(dir, "app/certs/server.pem"), config.Cert)
+	assert.Equal(t, Path(filepath.Join(dir, "app/certs/server.key")), config.Key)
+	assert.Equal(t, []Path{Path(filepath.Join(dir, "app/plugins/a.so")), "/opt/b.so"},
+		config.Plugins)
+	assert.Equal(t, "some/name", config.Name)
+	assert.Equal(t, "/home/me/cache", config.Cache)
+	assert.Equal(t, filepath.Join(cwd, "app.log"), config.LogFile)
+	assert.Equal(t, Path("/home/me/data"), config.Data)
+
+	// Paths that must exist are checked for every source.
+	setOS([]string{"--cert", "doesntexist.pem"}, nil)
+	err = Load(&config, Conf{
+		FileDefaultFilename: filepath.Join(dir, "app", "app.yaml"),
+	})
+	var optErr *OptionError
+	require.True(t, errors.As(err, &optErr))
+	assert.Equal(t, "cert", optErr.OptionID)
+	assert.Equal(t, SourceFlag, optErr.Source)
+	assert.Contains(t, optErr.Error(), "does not exist")
+}
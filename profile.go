@@ -0,0 +1,160 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultProfileKey is the default key in config files for profile sections.
+const defaultProfileKey = "profiles"
+
+// findProfile finds out which profile is selected.  Like for the config file,
+// it looks in the command line flags and the environment variables.  If the
+// profile is not specified there, the default value of the profile variable
+// is used.
+func findProfile(s *setup) (string, error) {
+	if s.conf.ProfileVariable == "" {
+		return "", nil
+	}
+
+	// Check if the config struct defined a variable for the profile.
+	var profileOpt *option
+	for _, opt := range s.opts {
+		if opt.id == s.conf.ProfileVariable {
+			profileOpt = opt
+			break
+		}
+	}
+	if profileOpt == nil {
+		panic(fmt.Errorf("profile variable name provided (%v), "+
+			"but not defined in config struct", s.conf.ProfileVariable))
+	}
+
+	source := SourceFlag
+	profile, err := lookupOptionFlag(s, profileOpt)
+	if err != nil {
+		return "", err
+	}
+	if profile == "" && !s.conf.EnvDisable {
+		source = SourceEnv
+		profile, err = lookupOptionEnv(s, profileOpt)
+		if err != nil {
+			return "", err
+		}
+	}
+	if profile == "" {
+		return profileOpt.defaul, nil
+	}
+
+	if len(s.conf.Profiles) > 0 && !containsString(s.conf.Profiles, profile) {
+		return "", &OptionError{
+			OptionID: profileOpt.fullID(),
+			Source:   source,
+			Err: fmt.Errorf("unknown profile '%v'%v", profile,
+				didYouMean(suggestions(profile, s.conf.Profiles))),
+		}
+	}
+
+	return profile, nil
+}
+
+// containsString returns whether the string is in the slice.
+func containsString(slice []string, str string) bool {
+	for _, s := range slice {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// profileFilePath returns the path of the overlay file for the profile next
+// to the config file, like app.prod.yaml for app.yaml.
+func profileFilePath(configFilePath, profile string) string {
+	ext := filepath.Ext(configFilePath)
+	return strings.TrimSuffix(configFilePath, ext) + "." + profile + ext
+}
+
+// decodeProfileFile decodes the overlay file for the selected profile.  If
+// there is no such file, an empty map is returned.
+func decodeProfileFile(s *setup) (map[string]interface{}, map[string]Position, error) {
+	if s.profile == "" || s.configFilePath == "" {
+		return nil, nil, nil
+	}
+
+	path := profileFilePath(s.configFilePath, s.profile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	// The overlay file has the same format as the config file.
+	return readAndDecodeFile(s, path, nil, true)
+}
+
+// applyProfile removes the profile sections from the map and merges the
+// section of the selected profile into it.  The positions of the keys in the
+// section are added as the positions of the keys they override.
+func applyProfile(s *setup, m map[string]interface{}, positions map[string]Position) error {
+	key := s.conf.ProfileKey
+	if key == "" {
+		key = defaultProfileKey
+	}
+
+	val, set := m[key]
+	if !set {
+		return nil
+	}
+	delete(m, key)
+
+	sections, ok := val.(map[string]interface{})
+	if !ok {
+		return &DecodeError{s.configFilePath,
+			fmt.Errorf("profile sections under '%v' must be a map", key)}
+	}
+	for name := range sections {
+		s.fileProfiles = append(s.fileProfiles, name)
+	}
+
+	if s.profile == "" {
+		return nil
+	}
+	section, set := sections[s.profile]
+	if !set {
+		return nil
+	}
+	sectionMap, ok := section.(map[string]interface{})
+	if !ok {
+		return &DecodeError{s.configFilePath,
+			fmt.Errorf("profile section '%v' must be a map", s.profile)}
+	}
+	mergeMaps(m, sectionMap)
+
+	prefix := key + "." + s.profile + "."
+	for k, pos := range positions {
+		if strings.HasPrefix(k, prefix) {
+			positions[strings.TrimPrefix(k, prefix)] = pos
+		}
+	}
+	return nil
+}
+
+// availableProfiles returns the profiles from Conf.Profiles and the profiles
+// that were found in the config file, sorted and without duplicates.
+func availableProfiles(s *setup) []string {
+	seen := make(map[string]bool)
+	var profiles []string
+	for _, p := range append(append([]string{}, s.conf.Profiles...), s.fileProfiles...) {
+		if !seen[p] {
+			seen[p] = true
+			profiles = append(profiles, p)
+		}
+	}
+	sort.Strings(profiles)
+	return profiles
+}
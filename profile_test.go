@@ -0,0 +1,167 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type profileConfig struct {
+	Profile  string `default:"dev"`
+	Name     string
+	Port     int
+	Database struct {
+		Host string
+		User string
+	}
+}
+
+func TestProfileFilePath(t *testing.T) {
+	assert.Equal(t, "/etc/app/app.prod.yaml", profileFilePath("/etc/app/app.yaml", "prod"))
+	assert.Equal(t, "/etc/app/app.prod", profileFilePath("/etc/app/app", "prod"))
+}
+
+func TestLoad_Profiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app.yaml": "name: app\nport: 80\n" +
+			"database:\n  host: localhost\n  user: root\n" +
+			"profiles:\n" +
+			"  dev:\n    port: 8080\n" +
+			"  prod:\n    database:\n      host: db.prod\n",
+		"app.prod.yaml": "port: 443\n",
+	})
+	defer os.RemoveAll(dir)
+
+	load := func(conf Conf) (profileConfig, error) {
+		config := profileConfig{}
+		conf.FileDefaultFilename = filepath.Join(dir, "app.yaml")
+		conf.ProfileVariable = "profile"
+		conf.EnvPrefix = "APP_"
+		conf.FileStrict = true
+		return config, Load(&config, conf)
+	}
+
+	// The default profile.
+	setOS(nil, nil)
+	config, err := load(Conf{})
+	require.NoError(t, err)
+	assert.Equal(t, "dev", config.Profile)
+	assert.Equal(t, 8080, config.Port)
+	assert.Equal(t, "localhost", config.Database.Host)
+
+	// The profile from the environment, with both a section and an overlay
+	// file.
+	setOS(nil, map[string]string{"APP_PROFILE": "prod"})
+	config, err = load(Conf{})
+	require.NoError(t, err)
+	assert.Equal(t, "prod", config.Profile)
+	assert.Equal(t, "app", config.Name)
+	assert.Equal(t, 443, config.Port)
+	assert.Equal(t, "db.prod", config.Database.Host)
+	assert.Equal(t, "root", config.Database.User)
+
+	// The flag takes precedence over the environment.
+	setOS([]string{"--profile", "staging"}, map[string]string{"APP_PROFILE": "prod"})
+	config, err = load(Conf{})
+	require.NoError(t, err)
+	assert.Equal(t, "staging", config.Profile)
+	assert.Equal(t, 80, config.Port)
+
+	// Unknown profiles are rejected if the profiles are listed.
+	setOS([]string{"--profile", "prd"}, nil)
+	_, err = load(Conf{Profiles: []string{"dev", "staging", "prod"}})
+	var optErr *OptionError
+	require.True(t, errors.As(err, &optErr))
+	assert.Equal(t, "profile", optErr.OptionID)
+	assert.Equal(t, SourceFlag, optErr.Source)
+	assert.Contains(t, optErr.Error(), "unknown profile 'prd' (did you mean prod?)")
+}
+
+func TestLoad_ProfilePositions(t *testing.T) {
+	setOS(nil, nil)
+	config := profileConfig{}
+	err := LoadRawFile(&config, []byte("port: 80\nprofiles:\n  dev:\n    port: x\n"),
+		Conf{FileDecoder: DecoderYAML, ProfileVariable: "profile"})
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 4, parseErr.Position.Line)
+}
+
+func TestHelpMessage_Profiles(t *testing.T) {
+	config := profileConfig{}
+	s := &setup{
+		conf: &Conf{
+			ProfileVariable: "profile",
+			Profiles:        []string{"prod", "dev"},
+		},
+		fileProfiles: []string{"staging", "dev"},
+	}
+	require.NoError(t, inspectConfigStructure(s, &config))
+
+	var buf bytes.Buffer
+	writeHelpMessage(s, &buf, false)
+	assert.Contains(t, buf.String(), "Available profiles: dev, prod, staging\n")
+}
+
+func TestLoad_ProfileFromDotEnv(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app.yaml": "name: base\nprofiles:\n  prod:\n    name: prod\n",
+		".env":     "APP_PROFILE=prod\n",
+	})
+	defer os.RemoveAll(dir)
+	setOS(nil, nil)
+
+	config := profileConfig{}
+	require.NoError(t, Load(&config, Conf{
+		FileDefaultFilename: filepath.Join(dir, "app.yaml"),
+		ProfileVariable:     "profile",
+		EnvPrefix:           "APP_",
+		EnvFile:             filepath.Join(dir, ".env"),
+	}))
+	assert.Equal(t, "prod", config.Profile)
+	assert.Equal(t, "prod", config.Name)
+
+	// The real environment takes precedence over the dotenv file.
+	setOS(nil, map[string]string{"APP_PROFILE": "dev"})
+	config = profileConfig{}
+	require.NoError(t, Load(&config, Conf{
+		FileDefaultFilename: filepath.Join(dir, "app.yaml"),
+		ProfileVariable:     "profile",
+		EnvPrefix:           "APP_",
+		EnvFile:             filepath.Join(dir, ".env"),
+	}))
+	assert.Equal(t, "dev", config.Profile)
+	assert.Equal(t, "base", config.Name)
+}
+
+func TestLoad_ConfigFileFromDotEnv(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"other.yaml": "name: other\n",
+	})
+	defer os.RemoveAll(dir)
+	envFile := filepath.Join(dir, ".env")
+	require.NoError(t, ioutil.WriteFile(envFile,
+		[]byte("APP_CONFIG="+filepath.Join(dir, "other.yaml")+"\n"), 0644))
+	setOS(nil, nil)
+
+	config := struct {
+		Config string
+		Name   string
+	}{}
+	require.NoError(t, Load(&config, Conf{
+		ConfigFileVariable: "config",
+		EnvPrefix:          "APP_",
+		EnvFile:            envFile,
+	}))
+	assert.Equal(t, "other", config.Name)
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DecoderProperties is the decoding function for Java-style .properties
+// config files.
+//
+// Keys are separated from their values by =, : or whitespace.  Lines starting
+// with # or ! are comments and lines ending in a backslash continue on the
+// next line.  The escape sequences \t, \n, \r, \f and \uXXXX are supported,
+// any other escaped character is taken literally.  Dotted keys are split into
+// nested maps, so that "database.pool.size" sets the size option of the pool
+// struct inside the database struct.  If a key is repeated, the last value is
+// used.
+//
+// All values are decoded as strings, which are parsed into the type of the
+// config variable like values from environment variables.
+var DecoderProperties FileDecoderFn = func(c []byte) (map[string]interface{}, error) {
+	m, _, err := parseProperties(c)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing properties config file: %v", err)
+	}
+	return m, nil
+}
+
+// PositionsProperties is the FilePositionsFn for .properties config files.
+var PositionsProperties FilePositionsFn = func(c []byte) (map[string]Position, error) {
+	_, positions, err := parseProperties(c)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing properties config file: %v", err)
+	}
+	return positions, nil
+}
+
+// propertiesLine is a logical line of a .properties file, which can be
+// composed of multiple physical lines.
+type propertiesLine struct {
+	text   string
+	line   int // the line number of the first physical line
+	column int // the column of the first non-whitespace character
+}
+
+// propertiesLines splits the content of a .properties file in logical lines,
+// leaving out comments and blank lines and joining continued lines.
+func propertiesLines(c []byte) []propertiesLine {
+	physical := strings.Split(strings.Replace(string(c), "\r\n", "\n", -1), "\n")
+
+	var lines []propertiesLine
+	for i := 0; i < len(physical); i++ {
+		trimmed := strings.TrimLeft(physical[i], " \t\f")
+		if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '!' {
+			continue
+		}
+
+		l := propertiesLine{
+			line:   i + 1,
+			column: len(physical[i]) - len(trimmed) + 1,
+		}
+		for {
+			// A line is continued if it ends with an odd number of backslashes.
+			backslashes := len(trimmed) - len(strings.TrimRight(trimmed, "\\"))
+			if backslashes%2 == 0 || i+1 >= len(physical) {
+				l.text += trimmed
+				break
+			}
+			l.text += trimmed[:len(trimmed)-1]
+			i++
+			trimmed = strings.TrimLeft(physical[i], " \t\f")
+		}
+		lines = append(lines, l)
+	}
+
+	return lines
+}
+
+// unescapeProperties interprets the escape sequences in a key or value.
+func unescapeProperties(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("invalid unicode escape: \\%v", s[i:])
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape: \\%v", s[i:i+5])
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// splitPropertiesLine splits a logical line in its raw key and value.
+func splitPropertiesLine(text string) (string, string) {
+	end := 0
+	for ; end < len(text); end++ {
+		if text[end] == '\\' {
+			end++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", text[end]) >= 0 {
+			break
+		}
+	}
+	if end > len(text) {
+		end = len(text)
+	}
+
+	key, rest := text[:end], strings.TrimLeft(text[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	return key, rest
+}
+
+// parseProperties parses the .properties content into a nested map and the
+// positions of its keys.
+func parseProperties(c []byte) (map[string]interface{}, map[string]Position, error) {
+	result := make(map[string]interface{})
+	positions := make(map[string]Position)
+
+	for _, l := range propertiesLines(c) {
+		rawKey, rawValue := splitPropertiesLine(l.text)
+		key, err := unescapeProperties(rawKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %v: %v", l.line, err)
+		}
+		value, err := unescapeProperties(rawValue)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %v: %v", l.line, err)
+		}
+
+		// Unflatten the dotted key into nested maps.
+		parts := strings.Split(key, ".")
+		target, path := result, ""
+		for i, part := range parts {
+			if part == "" {
+				return nil, nil, fmt.Errorf("line %v: invalid key '%v'", l.line, key)
+			}
+			path += part
+			if _, found := positions[path]; !found {
+				positions[path] = Position{Line: l.line, Column: l.column}
+			}
+
+			if i == len(parts)-1 {
+				if _, isMap := target[part].(map[string]interface{}); isMap {
+					return nil, nil, fmt.Errorf("line %v: key '%v' conflicts "+
+						"with keys nested under it", l.line, key)
+				}
+				target[part] = value
+				break
+			}
+
+			sub, ok := target[part].(map[string]interface{})
+			if !ok {
+				if _, exists := target[part]; exists {
+					return nil, nil, fmt.Errorf("line %v: key '%v' conflicts "+
+						"with key '%v'", l.line, key, path)
+				}
+				sub = make(map[string]interface{})
+				target[part] = sub
+			}
+			target, path = sub, path+"."
+		}
+	}
+
+	return result, positions, nil
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoderProperties(t *testing.T) {
+	content := []byte(`# comment
+! also a comment
+name = my app
+database.host: db.local
+database.pool.size 10
+database.pool.size = 20
+path = C:\\data\\app
+greeting = hello \
+           world
+tab\ key = a\tb
+unicode = caf\u00e9
+empty =
+`)
+	m, err := DecoderProperties(content)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name": "my app",
+		"database": map[string]interface{}{
+			"host": "db.local",
+			"pool": map[string]interface{}{
+				"size": "20",
+			},
+		},
+		"path":     `C:\data\app`,
+		"greeting": "hello world",
+		"tab key":  "a\tb",
+		"unicode":  "café",
+		"empty":    "",
+	}, m)
+
+	positions, err := PositionsProperties(content)
+	require.NoError(t, err)
+	assert.Equal(t, Position{Line: 4, Column: 1}, positions["database"])
+	assert.Equal(t, Position{Line: 5, Column: 1}, positions["database.pool.size"])
+	assert.Equal(t, Position{Line: 8, Column: 1}, positions["greeting"])
+}
+
+func TestDecoderProperties_Invalid(t *testing.T) {
+	for _, content := range []string{
+		"a = 1\na.b = 2\n",
+		"a.b = 2\na = 1\n",
+		"a..b = 1\n",
+		"a = \\u12\n",
+	} {
+		_, err := DecoderProperties([]byte(content))
+		assert.Error(t, err, content)
+	}
+}
+
+func TestLoad_Properties(t *testing.T) {
+	config := struct {
+		Database struct {
+			Host string
+			Pool struct {
+				Size int
+			}
+		}
+	}{}
+
+	s := &setup{configFilePath: "/etc/app.properties", conf: &Conf{}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+	require.NoError(t, parseFileContent(s, []byte(
+		"database.host=db\ndatabase.pool.size=10\n")))
+	assert.Equal(t, "db", config.Database.Host)
+	assert.Equal(t, 10, config.Database.Pool.Size)
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// SearchPathXDG returns the directory for the app in $XDG_CONFIG_HOME, or an
+// empty string if XDG_CONFIG_HOME is not set.
+func SearchPathXDG(app string) string {
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" {
+		return ""
+	}
+	return filepath.Join(xdg, app)
+}
+
+// SearchPathUserConfig returns the directory for the app in ~/.config, or an
+// empty string if the home directory is unknown.
+func SearchPathUserConfig(app string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", app)
+}
+
+// SearchPathSystem returns the directory for the app in /etc.
+func SearchPathSystem(app string) string {
+	return filepath.Join("/etc", app)
+}
+
+// SearchPathExecutable returns the directory of the running executable, or
+// an empty string if it is unknown.
+func SearchPathExecutable() string {
+	exec, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	return filepath.Dir(exec)
+}
+
+// StandardSearchPaths returns the usual directories to look for the config
+// file of the app in, in order of priority:
+//  - $XDG_CONFIG_HOME/app
+//  - ~/.config/app
+//  - /etc/app
+//  - the directory of the executable
+// Unknown directories and duplicates are left out.
+func StandardSearchPaths(app string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, path := range []string{
+		SearchPathXDG(app),
+		SearchPathUserConfig(app),
+		SearchPathSystem(app),
+		SearchPathExecutable(),
+	} {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// configFileCandidates returns the absolute paths where the default config
+// file is looked for, in order of priority.
+func configFileCandidates(s *setup) []string {
+	filename := s.conf.FileDefaultFilename
+	if filename == "" {
+		return nil
+	}
+
+	if filepath.IsAbs(filename) || len(s.conf.FileSearchPaths) == 0 {
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			return nil
+		}
+		return []string{abs}
+	}
+
+	var candidates []string
+	for _, dir := range s.conf.FileSearchPaths {
+		if dir == "" {
+			continue
+		}
+		abs, err := filepath.Abs(filepath.Join(dir, filename))
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, abs)
+	}
+	return candidates
+}
+
+// findDefaultConfigFile returns the first location of the default config file
+// that exists.  If there is none, the first location is returned so that the
+// absence of the config file can be handled like before.
+func findDefaultConfigFile(s *setup) string {
+	s.configFileSearched = configFileCandidates(s)
+	for _, candidate := range s.configFileSearched {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+
+	if len(s.configFileSearched) == 0 {
+		return ""
+	}
+	return s.configFileSearched[0]
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchPaths(t *testing.T) {
+	setOS(nil, map[string]string{"HOME": "/home/me"})
+	assert.Equal(t, "", SearchPathXDG("app"))
+	assert.Equal(t, "/home/me/.config/app", SearchPathUserConfig("app"))
+	assert.Equal(t, "/etc/app", SearchPathSystem("app"))
+	assert.NotEqual(t, "", SearchPathExecutable())
+
+	paths := StandardSearchPaths("app")
+	assert.Equal(t, []string{"/home/me/.config/app", "/etc/app"}, paths[:2])
+
+	setOS(nil, map[string]string{"HOME": "/home/me", "XDG_CONFIG_HOME": "/xdg"})
+	paths = StandardSearchPaths("app")
+	assert.Equal(t, []string{"/xdg/app", "/home/me/.config/app", "/etc/app"}, paths[:3])
+}
+
+func TestLoad_FileSearchPaths(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"second/app.yaml": "name: second\n",
+		"third/app.yaml":  "name: third\n",
+	})
+	defer os.RemoveAll(dir)
+	setOS(nil, nil)
+
+	searchPaths := []string{
+		filepath.Join(dir, "first"),
+		"",
+		filepath.Join(dir, "second"),
+		filepath.Join(dir, "third"),
+	}
+
+	var used string
+	config := struct{ Name string }{}
+	require.NoError(t, Load(&config, Conf{
+		FileDefaultFilename: "app.yaml",
+		FileSearchPaths:     searchPaths,
+		FileUsed:            &used,
+	}))
+	assert.Equal(t, "second", config.Name)
+	assert.Equal(t, filepath.Join(dir, "second", "app.yaml"), used)
+
+	// A missing default config file is ignored unless it is required.
+	used = ""
+	require.NoError(t, Load(&config, Conf{
+		FileDefaultFilename: "other.yaml",
+		FileSearchPaths:     searchPaths,
+		FileUsed:            &used,
+	}))
+	assert.Equal(t, "", used)
+
+	err := Load(&config, Conf{
+		FileDefaultFilename: "other.yaml",
+		FileSearchPaths:     searchPaths,
+		FileRequired:        true,
+	})
+	var notFound *FileNotFoundError
+	require.True(t, errors.As(err, &notFound))
+	assert.Equal(t, []string{
+		filepath.Join(dir, "first", "other.yaml"),
+		filepath.Join(dir, "second", "other.yaml"),
+		filepath.Join(dir, "third", "other.yaml"),
+	}, notFound.Searched)
+	assert.Contains(t, err.Error(), "config file other.yaml not found in any of: ")
+
+	// Absolute default filenames are not searched for.
+	require.NoError(t, Load(&config, Conf{
+		FileDefaultFilename: filepath.Join(dir, "third", "app.yaml"),
+		FileSearchPaths:     searchPaths,
+	}))
+	assert.Equal(t, "third", config.Name)
+}
+
+func TestHelpMessage_FileSearchPaths(t *testing.T) {
+	config := struct{ Name string }{}
+	s := &setup{conf: &Conf{
+		FileDefaultFilename: "app.yaml",
+		FileSearchPaths:     []string{"/etc/app", "/opt/app"},
+	}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+
+	var buf bytes.Buffer
+	writeHelpMessage(s, &buf, false)
+	assert.Contains(t, buf.String(), "The config file is searched for in:\n"+
+		"  /etc/app/app.yaml\n  /opt/app/app.yaml\n")
+}
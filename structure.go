@@ -18,14 +18,25 @@ const ( // The values for the struct field tags that we use.
 	fieldTagDefault     = "default"
 	fieldTagDescription = "desc"
 	fieldTagOpts        = "opts"
+	fieldTagGroup       = "group"
+	fieldTagAlias       = "alias"
+	fieldTagDeprecated  = "deprecated"
 )
 
 const ( // The values for the struct tag options.
-	fieldOptHidden = "hidden"
+	fieldOptHidden     = "hidden"
+	fieldOptPath       = "path"
+	fieldOptPathExists = "exists"
+	fieldOptAppend     = "append"
+	fieldOptReplace    = "replace"
+	fieldOptMerge      = "merge"
+	fieldOptAdvanced   = "advanced"
+	fieldOptSecret     = "secret"
 )
 
 var ( // Some type variables for comparison.
 	typeOfTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	typeOfTextMarshaler   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
 	typeOfByteSlice       = reflect.TypeOf([]byte{})
 )
 
@@ -40,13 +51,19 @@ type option struct {
 	defaultValue reflect.Value // the default value
 	isParent     bool          // is nested and has children
 	isMap        bool          // is a map type
+	source       Source        // the source that last set the value, if any
 
 	// Struct metadata specified by user.
 	id     string   // the identifier
 	short  string   // the shorthand to be used in CLI flags
 	defaul string   // the default value
 	desc   string   // the description
+	group  string   // the group in the help message
 	opts   []string // the field opts flags
+
+	aliases      [][]string // the old full IDs of the option, split in parts
+	isDeprecated bool       // the option has the deprecated tag
+	deprecated   string     // the deprecation message
 }
 
 // fullID returns the full ID of the option consisting of all IDs of its parents
@@ -65,6 +82,15 @@ func (o option) hasFieldOpt(opt string) bool {
 	return false
 }
 
+// optionIDs returns the IDs of all the given options.
+func optionIDs(opts []*option) []string {
+	ids := make([]string, len(opts))
+	for i, opt := range opts {
+		ids[i] = opt.id
+	}
+	return ids
+}
+
 // optionFromField creates a new option from the field information.
 func optionFromField(f reflect.StructField, parent *option) *option {
 	opt := new(option)
@@ -85,9 +111,16 @@ func optionFromField(f reflect.StructField, parent *option) *option {
 	opt.short = f.Tag.Get(fieldTagShort)
 	opt.defaul, opt.defaultSet = f.Tag.Lookup(fieldTagDefault)
 	opt.desc = f.Tag.Get(fieldTagDescription)
+	opt.group = f.Tag.Get(fieldTagGroup)
 	if opts, any := f.Tag.Lookup(fieldTagOpts); any {
 		opt.opts = strings.Split(opts, ",")
 	}
+	if aliases, any := f.Tag.Lookup(fieldTagAlias); any {
+		for _, alias := range strings.Split(aliases, ",") {
+			opt.aliases = append(opt.aliases, strings.Split(alias, "."))
+		}
+	}
+	opt.deprecated, opt.isDeprecated = f.Tag.Lookup(fieldTagDeprecated)
 
 	return opt
 }
@@ -152,6 +185,13 @@ func createOptionsFromStruct(v reflect.Value, parent *option) ([]*option, []*opt
 			}
 		}
 
+		if err := checkMergeOpts(opt); err != nil {
+			return nil, nil, err
+		}
+		if err := checkPathOpts(opt); err != nil {
+			return nil, nil, err
+		}
+
 		opts = append(opts, opt)
 		allOpts = append(allOpts, append(allSubOpts, opt)...)
 	}
@@ -203,6 +243,14 @@ func inspectConfigStructure(s *setup, c interface{}) error {
 		}
 	}
 
+	if err := checkAliases(allOpts); err != nil {
+		return err
+	}
+
+	if err := checkVersionFlag(s, allOpts); err != nil {
+		return err
+	}
+
 	s.opts = opts
 	s.allOpts = allOpts
 	return nil
@@ -10,6 +10,7 @@ import (
 	"encoding/csv"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -89,6 +90,23 @@ func parseFloat(v reflect.Value, s string) error {
 	return nil
 }
 
+// isSimpleType returns whether values of type t can be parsed using
+// parseSimpleValue.
+func isSimpleType(t reflect.Type) bool {
+	if t.Implements(typeOfTextUnmarshaler) || t == typeOfByteSlice {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Interface, reflect.Bool,
+		reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8,
+		reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		return true
+	}
+	return false
+}
+
 // parseSimpleValue parses values other than structs, slices (except []byte),
 // and encoding.TextUnmarshaler and stores them in v.
 func parseSimpleValue(v reflect.Value, s string) error {
@@ -197,8 +215,8 @@ keys:
 			}
 		}
 		// No option found for the key.
-		return fmt.Errorf("found no option with id '%v' in nested struct slice",
-			key.String())
+		return fmt.Errorf("found no option with id '%v' in nested struct slice%v",
+			key.String(), didYouMean(suggestions(key.String(), optionIDs(opts))))
 	}
 
 	return nil
@@ -213,6 +231,48 @@ func isKindOrPtrTo(t reflect.Type, k reflect.Kind) bool {
 	return t.Kind() == reflect.Ptr && t.Elem().Kind() == k
 }
 
+// mapToStructSlice converts the map from into a slice of maps for a slice of
+// structs of type t.  Decoders like the HCL decoder produce maps for blocks:
+// a single block is a map of its fields and labeled blocks are maps keyed by
+// their labels.  If all keys of the map are IDs of fields of the struct, the
+// map is the single element of the slice.  Otherwise, if all values are maps,
+// they are the elements of the slice in the lexical order of their keys.
+func mapToStructSlice(from reflect.Value, t reflect.Type) reflect.Value {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	fieldIDs := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.PkgPath == "" {
+			fieldIDs[optionFromField(f, nil).id] = true
+		}
+	}
+
+	keys := make([]string, 0, from.Len())
+	allFields, allMaps := true, true
+	for _, key := range from.MapKeys() {
+		keys = append(keys, key.String())
+		allFields = allFields && fieldIDs[key.String()]
+		val := from.MapIndex(key)
+		if val.Kind() == reflect.Interface {
+			val = val.Elem()
+		}
+		allMaps = allMaps && val.Kind() == reflect.Map
+	}
+
+	var elems []interface{}
+	if allFields || !allMaps {
+		elems = []interface{}{from.Interface()}
+	} else {
+		sort.Strings(keys)
+		for _, key := range keys {
+			elems = append(elems, from.MapIndex(reflect.ValueOf(key)).Interface())
+		}
+	}
+	return reflect.ValueOf(elems)
+}
+
 // convertSlice converts the slice from into the slice to by converting all the
 // individual elements.
 func convertSlice(from, to reflect.Value) error {
@@ -241,6 +301,16 @@ func convertSlice(from, to reflect.Value) error {
 			continue
 		}
 
+		// Decoders for formats without types, like INI, produce strings that
+		// need to be parsed like values from env vars and flags.
+		if elem.Kind() == reflect.String && subType.Kind() != reflect.String &&
+			isSimpleType(subType) {
+			if err := parseSimpleValue(converted.Index(i), elem.String()); err != nil {
+				return err
+			}
+			continue
+		}
+
 		if !elem.Type().ConvertibleTo(subType) {
 			return convertibleError(elem, subType)
 		}
@@ -304,3 +374,83 @@ func readAsCSV(val string) ([]string, error) {
 //	w.Flush()
 //	return strings.TrimSuffix(b.String(), "\n"), nil
 //}
+
+// editDistance returns the optimal string alignment distance between a and b.
+// This is the Levenshtein distance where swapping two adjacent characters also
+// counts as a single edit, which catches the most common typos.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, minInt(d[i][j-1]+1, d[i-1][j-1]+cost))
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+// minInt returns the smallest of two integers.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxSuggestions is the maximum number of suggestions returned by suggestions.
+const maxSuggestions = 3
+
+// suggestions returns the candidates that are close enough to word to be a
+// likely intended alternative, closest first.  Candidates at equal distance
+// are sorted alphabetically.
+func suggestions(word string, candidates []string) []string {
+	maxDist := len(word) / 4
+	if maxDist < 1 {
+		maxDist = 1
+	}
+
+	type scored struct {
+		candidate string
+		distance  int
+	}
+	var found []scored
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if c == "" || c == word || seen[c] {
+			continue
+		}
+		seen[c] = true
+		if dist := editDistance(word, c); dist <= maxDist {
+			found = append(found, scored{c, dist})
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].distance != found[j].distance {
+			return found[i].distance < found[j].distance
+		}
+		return found[i].candidate < found[j].candidate
+	})
+
+	result := make([]string, 0, maxSuggestions)
+	for i := 0; i < len(found) && i < maxSuggestions; i++ {
+		result = append(result, found[i].candidate)
+	}
+	return result
+}
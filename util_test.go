@@ -0,0 +1,28 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("listen", "listen"))
+	assert.Equal(t, 1, editDistance("listen-adress", "listen-address"))
+	assert.Equal(t, 1, editDistance("prot", "port"))
+	assert.Equal(t, 3, editDistance("", "abc"))
+	assert.Equal(t, 3, editDistance("kitten", "sitting"))
+}
+
+func TestSuggestions(t *testing.T) {
+	candidates := []string{"listen-address", "listen-port", "log-level", "help"}
+
+	assert.Equal(t, []string{"listen-address"},
+		suggestions("listen-adress", candidates))
+	assert.Equal(t, []string{"help"}, suggestions("hlep", candidates))
+	assert.Empty(t, suggestions("database", candidates))
+}
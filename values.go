@@ -31,6 +31,8 @@ func setValueByString(v reflect.Value, s string) error {
 // If not, but the value is a string, it is passed to setValueByString.
 // If not, and both v and the option's value are is a slice, we try converting
 // the slice elements to the right elemens of the options slice.
+// If the option's value is a slice of structs and v is a map, the map is
+// converted into a slice first, see mapToStructSlice.
 func setValue(toSet, v reflect.Value) error {
 	t := toSet.Type()
 	if v.Type().AssignableTo(t) {
@@ -51,6 +53,11 @@ func setValue(toSet, v reflect.Value) error {
 		return convertSlice(v, toSet)
 	}
 
+	if isSlice(toSet) && v.Type().Kind() == reflect.Map &&
+		isKindOrPtrTo(t.Elem(), reflect.Struct) {
+		return convertSlice(mapToStructSlice(v, t.Elem()), toSet)
+	}
+
 	return convertibleError(v, toSet.Type())
 }
 
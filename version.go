@@ -0,0 +1,85 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const defaultVersionDescription = "print the version"
+
+// ErrVersion is returned by the Load functions when the --version flag is
+// provided and Conf.VersionNoExit is set.  The version has then already been
+// printed.
+var ErrVersion = errors.New("version requested")
+
+// versionEnabled returns whether the --version flag is available.
+func versionEnabled(s *setup) bool {
+	return !s.conf.FlagDisable && (s.conf.Version != "" || s.conf.VersionFunc != nil)
+}
+
+// checkVersionFlag checks that no option uses the ID of the --version flag if
+// it is enabled.
+func checkVersionFlag(s *setup, allOpts []*option) error {
+	if s.conf == nil || !versionEnabled(s) {
+		return nil
+	}
+	for _, opt := range allOpts {
+		if opt.fullID() == "version" {
+			return errors.New("config variable version conflicts with the " +
+				"--version flag, rename it or unset Conf.Version and " +
+				"Conf.VersionFunc")
+		}
+	}
+	return nil
+}
+
+// versionString returns the version of the program from the conf.
+func versionString(s *setup) string {
+	if s.conf.VersionFunc != nil {
+		return s.conf.VersionFunc()
+	}
+	return s.conf.Version
+}
+
+// versionHelpOption creates the model of the --version flag for the help
+// message.
+func versionHelpOption(s *setup) *HelpOption {
+	desc := s.conf.VersionDescription
+	if desc == "" {
+		desc = defaultVersionDescription
+	}
+	return &HelpOption{
+		ID:          "version",
+		FullID:      "version",
+		Type:        "bool",
+		Description: desc,
+		Builtin:     true,
+	}
+}
+
+// writeVersion writes the version to the writer.
+func writeVersion(s *setup, w io.Writer) {
+	fmt.Fprintln(w, versionString(s))
+}
+
+// handleVersionFlag prints the version.  It then exits the program, or returns
+// ErrVersion if Conf.VersionNoExit is set.
+// The version is only printed once, even if the flags are parsed several times.
+func handleVersionFlag(s *setup) error {
+	if s.versionPrinted {
+		return ErrVersion
+	}
+	s.versionPrinted = true
+	writeVersion(s, os.Stdout)
+	if s.conf.VersionNoExit {
+		return ErrVersion
+	}
+	os.Exit(0)
+	return nil
+}
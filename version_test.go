@@ -0,0 +1,82 @@
+// Copyright (c) 2017 Steven Roose <steven@stevenroose.org>.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gonfig
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Version(t *testing.T) {
+	config := struct {
+		Port int
+	}{}
+
+	setOS([]string{"--port", "x", "--version"}, nil)
+	err := Load(&config, Conf{Version: "1.2.3", VersionNoExit: true})
+	assert.Equal(t, ErrVersion, err)
+
+	// Without a version, the flag is unknown.
+	setOS([]string{"--version"}, nil)
+	err = Load(&config, Conf{})
+	var unknownErr *UnknownFlagError
+	require.True(t, errors.As(err, &unknownErr))
+
+	// Flags after -- are not handled.
+	setOS([]string{"--port", "1", "--", "--version"}, nil)
+	require.NoError(t, Load(&config, Conf{Version: "1.2.3", VersionNoExit: true}))
+	assert.Equal(t, 1, config.Port)
+
+	// The version flag is suggested for typos.
+	setOS([]string{"--versoin"}, nil)
+	err = Load(&config, Conf{Version: "1.2.3"})
+	require.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, []string{"--version"}, unknownErr.Suggestions["--versoin"])
+}
+
+func TestLoad_VersionConflict(t *testing.T) {
+	config := struct {
+		Version int
+	}{}
+	setOS([]string{"--version", "2"}, nil)
+	assert.Panics(t, func() {
+		Load(&config, Conf{Version: "1.2.3"})
+	})
+
+	// Without a version, the option can be used.
+	require.NoError(t, Load(&config, Conf{}))
+	assert.Equal(t, 2, config.Version)
+
+	err := inspectConfigStructure(&setup{conf: &Conf{Version: "1.2.3"}}, &config)
+	assert.EqualError(t, err, "config variable version conflicts with the "+
+		"--version flag, rename it or unset Conf.Version and Conf.VersionFunc")
+}
+
+func TestWriteVersion(t *testing.T) {
+	s := &setup{conf: &Conf{
+		Version:     "1.2.3",
+		VersionFunc: func() string { return "app 2.0.0" },
+	}}
+	var buf bytes.Buffer
+	writeVersion(s, &buf)
+	assert.Equal(t, "app 2.0.0\n", buf.String())
+}
+
+func TestHelpMessage_Version(t *testing.T) {
+	config := struct{ Name string }{}
+	s := &setup{conf: &Conf{HelpMessage: "Usage:", Version: "1.2.3"}}
+	require.NoError(t, inspectConfigStructure(s, &config))
+
+	var buf bytes.Buffer
+	writeHelpMessage(s, &buf, false)
+	assert.Equal(t, "Usage:\n"+
+		"      --name string   \n"+
+		"  -h, --help          print this help menu\n"+
+		"      --version       print the version\n", buf.String())
+}